@@ -0,0 +1,44 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package main ...
+package main
+
+import (
+	"context"
+	"flag"
+
+	nodeupdater "github.com/IBM/vpc-node-label-updater/pkg/nodeupdater"
+	"go.uber.org/zap"
+)
+
+// runCleanup removes the labels managed by the updater, or restores their previous values, on the selected nodes.
+func runCleanup(args []string) {
+	flags := addClientFlags(flag.NewFlagSet("cleanup", flag.ExitOnError))
+	selector := flags.String("selector", "", "Label selector of the nodes to clean up. Empty selects all nodes")
+	restore := flags.Bool("restore", false, "Restore the values the managed labels had before the updater set them, instead of removing them")
+	dryRun := flags.Bool("dry-run", false, "Only log the nodes which would be cleaned up")
+	_ = flags.Parse(args) // #nosec G104: flag.ExitOnError exits on parse errors.
+
+	logger.Info("Cleaning up node labels managed by the updater", zap.String("selector", *selector), zap.Bool("restore", *restore), zap.Bool("dryRun", *dryRun))
+	k8sClient := newKubernetesClient()
+	options := nodeupdater.CleanupOptions{Selector: *selector, Restore: *restore, DryRun: *dryRun}
+	cleaned, err := nodeupdater.CleanupNodes(context.TODO(), k8sClient.Clientset, options, logger)
+	if err != nil {
+		logger.Fatal("Failed to clean up node labels", zap.Int("cleanedNodes", cleaned), zap.Error(err))
+	}
+	logger.Info("Cleaned up node labels", zap.Int("cleanedNodes", cleaned))
+}
@@ -0,0 +1,166 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package main ...
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+
+	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
+	nodeupdater "github.com/IBM/vpc-node-label-updater/pkg/nodeupdater"
+	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var (
+	kubeconfig       = flag.String("kubeconfig", "", "Path of a kubeconfig file, to run outside the cluster, e.g. from a workstation. Empty uses the in-cluster configuration, unless -context is set")
+	kubeContext      = flag.String("context", "", "Name of the kubeconfig context to use. Setting it without -kubeconfig loads the kubeconfig from KUBECONFIG or ~/.kube/config")
+	namespace        = flag.String("namespace", "kube-system", "With -kubeconfig or -context, namespace of the storage-secret-store secret, the configmaps and the leases of the updater. In the cluster, the pod namespace is used")
+	secretConfigFile = flag.String("secret-config-file", "", "Path of a local slclient.toml file holding the VPC credentials and endpoints, read instead of the storage-secret-store secret")
+	credentialsFile  = flag.String("credentials-file", "", "Path of a TOML or YAML file with the vpc section of slclient.toml: g2_api_key or g2_trusted_profile_id and g2_cr_token_file, g2_riaas_endpoint_url and g2_token_exchange_endpoint_url. Used instead of the secret provider, for clusters without the storage-secret-store secret. The environment variables of -credentials-env override its values")
+	credentialsEnv   = flag.Bool("credentials-env", false, "Read the VPC credentials from the "+nodeupdater.APIKeyEnv+" or "+nodeupdater.TrustedProfileEnv+" and "+nodeupdater.CRTokenFileEnv+", "+nodeupdater.RiaasEndpointEnv+" and "+nodeupdater.IAMEndpointEnv+" environment variables instead of the secret provider. The IAM endpoint defaults to the public one")
+	metadataToken    = flag.Bool("metadata-token", false, "Get the IAM token from the VPC metadata service of the node, exchanging the instance identity token through the trusted profile linked to the instance. The credentials of the other flags are the fallback when the metadata service can't issue a token")
+	metadataEndpoint = flag.String("metadata-endpoint", nodeupdater.DefaultMetadataEndpointURL, "URL of the VPC metadata service, with -metadata-token")
+	metadataProfile  = flag.String("metadata-trusted-profile-id", "", "ID of the trusted profile of the IAM token, with -metadata-token. Empty uses the profile linked to the instance")
+	vpcCABundle      = flag.String("vpc-ca-bundle", "", "Path of a PEM file of CA certificates trusted for the RIAAS and IAM requests in addition to the system ones, e.g. of an inspecting egress proxy. "+
+		"The TLS flags require -credentials-file, -credentials-env or -secret-config-file, the secret provider reading the storage-secret-store secret by default can't use them")
+	vpcClientCert    = flag.String("vpc-client-cert", "", "Path of the PEM client certificate of the RIAAS and IAM requests. Requires -vpc-client-key")
+	vpcClientKey     = flag.String("vpc-client-key", "", "Path of the PEM key of -vpc-client-cert")
+	vpcTLSMinVersion = flag.String("vpc-tls-min-version", "1.2", "Minimum TLS version of the RIAAS and IAM requests: 1.2 or 1.3")
+)
+
+// clientFlags select the cluster and the VPC credentials. They are accepted by all the subcommands.
+var clientFlags = []string{
+	"kubeconfig", "context", "namespace", "secret-config-file", "credentials-file", "credentials-env",
+	"metadata-token", "metadata-endpoint", "metadata-trusted-profile-id",
+	"vpc-ca-bundle", "vpc-client-cert", "vpc-client-key", "vpc-tls-min-version",
+}
+
+// addClientFlags adds the client flags to the flag set of a subcommand which doesn't accept all the global flags.
+func addClientFlags(flags *flag.FlagSet) *flag.FlagSet {
+	for _, name := range clientFlags {
+		f := flag.Lookup(name)
+		flags.Var(f.Value, f.Name, f.Usage)
+	}
+	return flags
+}
+
+// outOfCluster returns true if a kubeconfig is used instead of the in-cluster configuration.
+func outOfCluster() bool {
+	return *kubeconfig != "" || *kubeContext != ""
+}
+
+// restConfig returns the configuration of the clients, from the kubeconfig when running out of the cluster.
+func restConfig() (*rest.Config, error) {
+	if !outOfCluster() {
+		return rest.InClusterConfig()
+	}
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = *kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: *kubeContext}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+}
+
+// newKubernetesClient returns the client of the cluster with the namespace of the updater.
+func newKubernetesClient() k8s_utils.KubernetesClient {
+	if !outOfCluster() {
+		k8sClient, err := k8s_utils.Getk8sClientSet()
+		if err != nil {
+			logger.Fatal("Failed to kubernetes create client set", zap.Error(err))
+		}
+		return k8sClient
+	}
+	config, err := restConfig()
+	if err != nil {
+		logger.Fatal("Failed to load kubeconfig", zap.String("kubeconfig", *kubeconfig), zap.String("context", *kubeContext), zap.Error(err))
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		logger.Fatal("Failed to kubernetes create client set", zap.Error(err))
+	}
+	logger.Info("Running outside the cluster", zap.String("host", config.Host), zap.String("namespace", *namespace))
+	return k8s_utils.KubernetesClient{Namespace: *namespace, Clientset: clientset}
+}
+
+// secretConfigSource returns the source of the VPC credentials: the metadata service with -metadata-token, falling
+// back to the credentials of the other flags.
+func secretConfigSource(k8sClient *k8s_utils.KubernetesClient) nodeupdater.SecretConfigSource {
+	source := credentialsSource(k8sClient)
+	if *metadataToken {
+		config := nodeupdater.MetadataConfig{
+			EndpointURL:      *metadataEndpoint,
+			TrustedProfileID: *metadataProfile,
+			RiaasEndpointURL: os.Getenv(nodeupdater.RiaasEndpointEnv),
+		}
+		source = nodeupdater.MetadataSource(config, source, logger)
+	}
+	if client := vpcHTTPClient(); client != nil {
+		source = nodeupdater.WithHTTPClient(source, client)
+	}
+	return source
+}
+
+// credentialsSource returns the source of the credentials: -credentials-file or -credentials-env, the local
+// -secret-config-file, or the secret provider of the cluster.
+func credentialsSource(k8sClient *k8s_utils.KubernetesClient) nodeupdater.SecretConfigSource {
+	if *credentialsFile != "" || *credentialsEnv {
+		if *secretConfigFile != "" {
+			logger.Fatal("-secret-config-file can't be combined with -credentials-file or -credentials-env")
+		}
+		credentials, err := nodeupdater.LoadCredentials(*credentialsFile)
+		if err != nil {
+			logger.Fatal("Failed to load VPC credentials", zap.String("file", *credentialsFile), zap.Error(err))
+		}
+		return nodeupdater.CredentialsSource(credentials, logger)
+	}
+	if *secretConfigFile == "" {
+		// The secret provider requests its IAM tokens with its own client, which would ignore the TLS flags.
+		if vpcTLSOptions().IsSet() {
+			logger.Fatal("-vpc-ca-bundle, -vpc-client-cert, -vpc-client-key and -vpc-tls-min-version don't apply to the secret provider, " +
+				"give the credentials with -credentials-file, -credentials-env or -secret-config-file")
+		}
+		return nodeupdater.SecretProviderSource(k8sClient, logger)
+	}
+	return nodeupdater.LocalSecretSource(*secretConfigFile, logger)
+}
+
+// vpcTLSOptions returns the TLS options of the VPC requests set by the flags.
+func vpcTLSOptions() nodeupdater.VPCTLSOptions {
+	return nodeupdater.VPCTLSOptions{
+		CABundleFile:   *vpcCABundle,
+		ClientCertFile: *vpcClientCert,
+		ClientKeyFile:  *vpcClientKey,
+		MinVersion:     *vpcTLSMinVersion,
+	}
+}
+
+// vpcHTTPClient returns the client of the VPC requests if any of the TLS flags is set, nil otherwise.
+func vpcHTTPClient() *http.Client {
+	options := vpcTLSOptions()
+	if !options.IsSet() {
+		return nil
+	}
+	client, err := nodeupdater.NewVPCHTTPClient(options)
+	if err != nil {
+		logger.Fatal("Invalid TLS configuration of VPC requests", zap.Error(err))
+	}
+	return client
+}
@@ -0,0 +1,234 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package main ...
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	nodeupdater "github.com/IBM/vpc-node-label-updater/pkg/nodeupdater"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+var (
+	controllerWorkers      = flag.Int("workers", 2, "With the controller subcommand, number of nodes reconciled concurrently")
+	controllerResyncPeriod = flag.Duration("resync-period", 10*time.Minute, "With the controller subcommand, how often the labels of all nodes are verified")
+	leaderElect            = flag.Bool("leader-elect", true, "With the controller subcommand, elect a leader among the replicas so that only one reconciles nodes")
+	leaderElectLeaseName   = flag.String("leader-elect-lease-name", "vpc-node-label-updater", "Name of the lease in the pod namespace used for leader election, and prefix of the shard membership leases")
+	leaseDuration          = flag.Duration("leader-elect-lease-duration", 15*time.Second, "How long non-leader replicas wait before taking over the lease of a leader which stopped renewing it. Also how long a shard member stays live without renewing its lease")
+	renewDeadline          = flag.Duration("leader-elect-renew-deadline", 10*time.Second, "How long the leader retries renewing its lease before giving up leadership")
+	retryPeriod            = flag.Duration("leader-elect-retry-period", 2*time.Second, "How often replicas try to acquire or renew their lease")
+	healthProbeBindAddress = flag.String("health-probe-bind-address", ":8081", "With the controller subcommand, address on which /healthz and /readyz are served. Empty disables the probes")
+	workqueueStallTimeout  = flag.Duration("workqueue-stall-timeout", 10*time.Minute, "With the controller subcommand, /healthz fails when no queued node was reconciled for this long. Must exceed the retries of VPC requests")
+	instanceHealthInterval = flag.Duration("instance-health-interval", 5*time.Minute, "With the controller subcommand, how often the status of the VPC instance of every labeled node is checked. 0 disables the check")
+	instanceHealthTaint    = flag.String("instance-health-taint", nodeupdater.DefaultInstanceUnhealthyTaint, "With the controller subcommand, taint key[=value]:effect of the nodes whose VPC instance is missing, stopped or failed. Empty only sets the VPCInstanceHealthy node condition")
+	sharding               = flag.Bool("sharding", false, "With the controller subcommand, split the nodes between all the replicas by consistent hashing instead of electing a leader")
+	watchSecretConfig      = flag.Bool("watch-secret-config", true, "With the controller subcommand, reload the VPC credentials and endpoints when the storage-secret-store secret or the cluster-info configmap change")
+)
+
+// runController runs the updater as a cluster-wide controller reconciling the labels of all nodes. The nodes are
+// looked up in an instance cache, of one minute unless -instance-cache-ttl is set, so that a resync lists the
+// instances once instead of once per node.
+func runController(args []string) {
+	parseFlags(args)
+	logger.Info("Starting node label controller", zap.Bool("leaderElect", *leaderElect), zap.Bool("sharding", *sharding))
+	serveMetrics()
+	shutdownTracing := mustSetUpTracing(context.Background())
+	defer shutdownTracing()
+
+	k8sClient := newKubernetesClient()
+	identity := os.Getenv("POD_NAME")
+	if identity == "" {
+		var err error
+		if identity, err = os.Hostname(); err != nil {
+			logger.Fatal("Failed to get controller identity", zap.Error(err))
+		}
+	}
+
+	updater := newNodeLabelUpdater(context.Background(), &k8sClient)
+	if updater.InstanceCache == nil {
+		updater.InstanceCache = nodeupdater.NewInstanceCache(time.Minute)
+	}
+	var stopRecorder func()
+	updater.Recorder, stopRecorder = newEventRecorder(k8sClient.Clientset)
+	defer stopRecorder()
+	defer saveInstanceCache(&k8sClient, updater.InstanceCache)
+	// Set on the updater before the controller copies it.
+	policyWatcher := newPolicyWatcher(updater)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+	if *watchSecretConfig {
+		reloader := nodeupdater.NewSecretConfigReloader(k8sClient.Clientset, k8sClient.Namespace, secretConfigSource(&k8sClient),
+			updater.StorageSecretConfig, logger)
+		updater.CurrentSecretConfig = reloader.Current
+		go reloader.Run(ctx)
+	}
+
+	if *sharding {
+		membership := &nodeupdater.ShardMembership{
+			K8sClient:     k8sClient.Clientset,
+			Logger:        logger,
+			Namespace:     k8sClient.Namespace,
+			Group:         *leaderElectLeaseName,
+			Identity:      identity,
+			LeaseDuration: *leaseDuration,
+			RenewPeriod:   *retryPeriod,
+		}
+		controller := nodeupdater.NewController(updater, *controllerResyncPeriod, membership)
+		membership.OnChange = controller.EnqueueAll
+		attachPolicyWatcher(policyWatcher, controller)
+		serveHealthProbes(controller, updater, nil)
+		go membership.Run(ctx)
+		runNodeLabelController(ctx, controller, policyWatcher)
+		return
+	}
+
+	controller := nodeupdater.NewController(updater, *controllerResyncPeriod, nil)
+	attachPolicyWatcher(policyWatcher, controller)
+	if !*leaderElect {
+		serveHealthProbes(controller, updater, nil)
+		runNodeLabelController(ctx, controller, policyWatcher)
+		return
+	}
+	// Fail liveness when the leader stopped renewing its lease for longer than the lease duration.
+	watchdog := leaderelection.NewLeaderHealthzAdaptor(*leaseDuration)
+	serveHealthProbes(controller, updater, watchdog)
+	controller.Start(ctx)
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: *leaderElectLeaseName, Namespace: k8sClient.Namespace},
+		Client:     k8sClient.Clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		ReleaseOnCancel: true,
+		LeaseDuration:   *leaseDuration,
+		RenewDeadline:   *renewDeadline,
+		RetryPeriod:     *retryPeriod,
+		Name:            *leaderElectLeaseName,
+		WatchDog:        watchdog,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				logger.Info("Acquired leadership", zap.String("identity", identity))
+				runNodeLabelController(ctx, controller, policyWatcher)
+			},
+			OnStoppedLeading: func() {
+				if ctx.Err() != nil {
+					logger.Info("Released leadership", zap.String("identity", identity))
+					return
+				}
+				// The informer and workqueue can't be restarted, let the pod restart as a candidate.
+				logger.Fatal("Lost leadership", zap.String("identity", identity))
+			},
+			OnNewLeader: func(leader string) {
+				logger.Info("Current leader", zap.String("leader", leader))
+			},
+		},
+	})
+}
+
+func runNodeLabelController(ctx context.Context, controller *nodeupdater.Controller, policyWatcher *nodeupdater.PolicyWatcher) {
+	if policyWatcher != nil {
+		// Without the policies, the nodes would be reconciled without their policy labels.
+		policyWatcher.Start(ctx)
+		if !cache.WaitForCacheSync(ctx.Done(), policyWatcher.HasSynced) {
+			logger.Error("Failed to sync node label policies")
+			return
+		}
+		go func() {
+			if cache.WaitForCacheSync(ctx.Done(), controller.HasSynced) {
+				policyWatcher.Run(ctx)
+			}
+		}()
+	}
+	if monitor := newInstanceHealthMonitor(controller); monitor != nil {
+		go func() {
+			// Check the nodes of the synced informer only, unknown nodes are not checked.
+			if cache.WaitForCacheSync(ctx.Done(), controller.HasSynced) {
+				monitor.Run(ctx)
+			}
+		}()
+	}
+	if err := controller.Run(ctx, *controllerWorkers); err != nil {
+		logger.Error("Node label controller stopped", zap.Error(err))
+	}
+}
+
+// newInstanceHealthMonitor returns the monitor of the VPC instances of the nodes reconciled by the controller,
+// or nil if disabled.
+func newInstanceHealthMonitor(controller *nodeupdater.Controller) *nodeupdater.InstanceHealthMonitor {
+	if *instanceHealthInterval <= 0 {
+		return nil
+	}
+	monitor := &nodeupdater.InstanceHealthMonitor{
+		Updater:  controller.Updater(),
+		Nodes:    controller.Nodes,
+		Interval: *instanceHealthInterval,
+	}
+	if *instanceHealthTaint != "" {
+		taint, err := nodeupdater.ParseTaint(*instanceHealthTaint)
+		if err != nil {
+			logger.Fatal("Invalid instance health taint", zap.Error(err))
+		}
+		monitor.Taint = taint
+	}
+	return monitor
+}
+
+// serveHealthProbes serves /healthz and /readyz in the background if enabled. Readiness requires the
+// node informer to be synced, a valid IAM token and a reachable RIAAS endpoint. Liveness fails when the
+// workqueue stalls, or when the leader stopped renewing its lease.
+func serveHealthProbes(controller *nodeupdater.Controller, updater *nodeupdater.VpcNodeLabelUpdater, watchdog *leaderelection.HealthzAdaptor) {
+	if *healthProbeBindAddress == "" {
+		return
+	}
+	liveness := map[string]nodeupdater.HealthCheck{
+		"workqueue": controller.StallHealthCheck(*workqueueStallTimeout),
+	}
+	if watchdog != nil {
+		liveness[watchdog.Name()] = watchdog.Check
+	}
+	readiness := map[string]nodeupdater.HealthCheck{
+		"informer-sync": controller.SyncedHealthCheck,
+		"iam-token": func(r *http.Request) error {
+			return updater.SecretConfig().TokenHealthCheck(r)
+		},
+		"riaas": nodeupdater.CachedHealthCheck(func(r *http.Request) error {
+			return updater.SecretConfig().RIAASHealthCheck(r)
+		}, 30*time.Second),
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", nodeupdater.HealthHandler(liveness))
+	mux.Handle("/readyz", nodeupdater.HealthHandler(readiness))
+	server := &http.Server{Addr: *healthProbeBindAddress, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("Health probe server stopped", zap.Error(err))
+		}
+	}()
+}
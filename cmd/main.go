@@ -21,60 +21,208 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
 	nodeupdater "github.com/IBM/vpc-node-label-updater/pkg/nodeupdater"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtimeu "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	logEncodingJSON    = "json"
+	logEncodingConsole = "console"
 )
 
 var (
 	logger *zap.Logger
+	// logLevel is shared by all the loggers, so that the level can be changed at runtime.
+	logLevel = zap.NewAtomicLevel()
+	// logOutput is where logs are written, stderr for the subcommands printing a report on stdout.
+	logOutput zapcore.WriteSyncer = zapcore.Lock(os.Stdout)
+
+	logLevelFlag  = flag.String("log-level", envOrDefault("LOG_LEVEL", "info"), "Initial log level: debug, info, warn or error. Defaults to the LOG_LEVEL environment variable. Can be changed at runtime on the /loglevel endpoint of -log-level-bind-address")
+	logFullDetail = flag.Bool("log-full-detail", false, "Log debug entries without redacting tokens, CRN account IDs and IP addresses. Entries at info level and above are always redacted")
+	logEncoding   = flag.String("log-encoding", envOrDefault("LOG_ENCODING", logEncodingJSON), "Log encoding: json or console. Defaults to the LOG_ENCODING environment variable")
+
+	instanceCacheTTL        = flag.Duration("instance-cache-ttl", 0, "Cache the VPC instance list for this long and serve node lookups from it. 0 disables the cache")
+	instanceCacheConfigMap  = flag.String("instance-cache-configmap", "", "Name of a configmap in the pod namespace used to share the instance cache between pods")
+	workerIDLabel           = flag.String("worker-id-label", string(nodeupdater.LegacyLabelWrite), "Whether the legacy worker-id label is written, kept or removed: write, keep or remove")
+	failureDomainLabels     = flag.String("failure-domain-labels", string(nodeupdater.LegacyLabelWrite), "Whether the legacy failure-domain.beta.kubernetes.io labels are written, kept or removed: write, keep, remove or auto to decide from the kubernetes server version")
+	failureDomainKeepFrom   = flag.String("failure-domain-labels-keep-from", "1.17", "With -failure-domain-labels=auto, kubernetes version from which the failure-domain labels are no longer written")
+	failureDomainRemoveFrom = flag.String("failure-domain-labels-remove-from", "", "With -failure-domain-labels=auto, kubernetes version from which the failure-domain labels are removed. Empty means never")
+	labelConflictPolicy     = flag.String("label-conflict-policy", string(nodeupdater.ConflictPolicyOverwrite), "What to do with a label already set to a different value by someone else: overwrite, keep or fail")
+	labelConflictPolicies   = flag.String("label-conflict-policies", "", "Comma separated list of label=policy pairs overriding -label-conflict-policy for individual labels")
+	metricsBindAddress      = flag.String("metrics-bind-address", "", "Address on which prometheus metrics are served, e.g. :8080. Empty disables the metrics endpoint")
+	logLevelBindAddress     = flag.String("log-level-bind-address", "", "Address on which the unauthenticated /loglevel endpoint changing the log level at runtime is served, e.g. 127.0.0.1:8081 to reach it with kubectl port-forward only. Empty disables the endpoint")
+	labelSnapshotStore      = flag.String("label-snapshot-store", nodeupdater.SnapshotStoreAnnotation, "Where the labels of a node are saved before they are changed, for the rollback subcommand: annotation, configmap or none")
+	labelSnapshotConfigMap  = flag.String("label-snapshot-configmap", "vpc-node-label-snapshots", "With -label-snapshot-store=configmap, name of the configmap in the pod namespace holding the label snapshots")
+	driftPolicy             = flag.String("drift-policy", string(nodeupdater.DriftPolicyCorrect), "What to do with labels of an already labeled node that differ from VPC: correct or report")
+	vpcIDFilter             = flag.String("vpc-id", "", "Only list the instances of this VPC. Empty lists the instances of all VPCs")
+	resourceGroupIDFilter   = flag.String("resource-group-id", "", "Only list the instances of this resource group. Empty lists the instances of all resource groups")
+	discoverInstanceFilter  = flag.Bool("discover-instance-filter", false, "Discover the VPC and resource group of the instance list filter from a node already labeled. -vpc-id and -resource-group-id take precedence")
+	credentialsGroupFilter  = flag.Bool("credentials-resource-group-filter", false, "Only list the instances of the resource group of the credentials, g2_resource_group_id or VPC_RESOURCE_GROUP_ID, unless -resource-group-id or -discover-instance-filter set one. Only for clusters whose workers all are in that resource group")
+
+	nodeNameFlag = flag.String("node", os.Getenv("NODE_NAME"), "Name of the node to label. Defaults to the NODE_NAME environment variable")
+	selectorFlag = flag.String("selector", "", "Label selector of the nodes to label instead of -node, e.g. from a workstation with -kubeconfig")
 )
 
+// labelModeFlags are only accepted when labeling nodes, subcommands define their own.
+var labelModeFlags = map[string]bool{"node": true, "selector": true}
+
 func init() {
 	_ = flag.Set("logtostderr", "true") // #nosec G104: Attempt to set flags for logging to stderr only on best-effort basis.Error cannot be usefully handled.
-	logger = setUpLogger()
+	// The logger is set up again once the flags are parsed, this one logs flag parsing.
+	var err error
+	if logger, err = setUpLogger(); err != nil {
+		logger, _ = newLogger(zapcore.InfoLevel, logEncodingJSON) // #nosec G104: the JSON encoder never fails.
+		logger.Error("Invalid logging configuration, logging at info level", zap.Error(err))
+	}
 	defer func() {
 		_ = logger.Sync() // #nosec G104: Attempt to logg sync only on best-effort basis.Error cannot be usefully handled.
 	}()
 }
 
-func setUpLogger() *zap.Logger {
+// setUpLogger creates the logger with the level and encoding of the flags, which default to the
+// LOG_LEVEL and LOG_ENCODING environment variables.
+func setUpLogger() (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(*logLevelFlag)
+	if err != nil {
+		return nil, err
+	}
+	return newLogger(level, *logEncoding)
+}
+
+func newLogger(level zapcore.Level, encoding string) (*zap.Logger, error) {
 	// Prepare a new logger
-	atom := zap.NewAtomicLevel()
 	encoderCfg := zap.NewProductionEncoderConfig()
 	encoderCfg.TimeKey = "timestamp"
 	encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
 
-	logger := zap.New(zapcore.NewCore(
-		zapcore.NewJSONEncoder(encoderCfg),
-		zapcore.Lock(os.Stdout),
-		atom,
-	), zap.AddCaller()).With(zap.String("watcher-name", "vpc-node-label-updater"))
+	var encoder zapcore.Encoder
+	switch encoding {
+	case logEncodingJSON:
+		encoder = zapcore.NewJSONEncoder(encoderCfg)
+	case logEncodingConsole:
+		encoder = zapcore.NewConsoleEncoder(encoderCfg)
+	default:
+		return nil, fmt.Errorf("invalid log encoding %q, must be %q or %q", encoding, logEncodingJSON, logEncodingConsole)
+	}
+
+	core := zapcore.NewCore(encoder, logOutput, logLevel)
+	logger := zap.New(nodeupdater.NewRedactingCore(core, nodeupdater.RedactionPolicy{FullDetail: *logFullDetail}), zap.AddCaller()).With(zap.String("watcher-name", "vpc-node-label-updater"))
+
+	logLevel.SetLevel(level)
+	return logger, nil
+}
+
+// parseFlags parses the command line flags and sets up the logger with the logging flags.
+func parseFlags(args []string) {
+	_ = flag.CommandLine.Parse(args) // #nosec G104: flag.ExitOnError exits on parse errors.
+	configured, err := setUpLogger()
+	if err != nil {
+		logger.Fatal("Invalid logging configuration", zap.Error(err))
+	}
+	logger = configured
+}
+
+// subcommandFlagSet returns a flag set of the subcommand, which also accepts the global flags.
+func subcommandFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		if !labelModeFlags[f.Name] {
+			flags.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+	return flags
+}
+
+// logToStderr sets up the logger again writing to stderr, keeping stdout for the output of the subcommand.
+func logToStderr() {
+	logOutput = zapcore.Lock(os.Stderr)
+	if configured, err := setUpLogger(); err == nil {
+		logger = configured
+	}
+}
 
-	atom.SetLevel(zap.InfoLevel)
-	return logger
+// envOrDefault returns the value of the environment variable, or the default if it is unset.
+func envOrDefault(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "cleanup":
+			runCleanup(os.Args[2:])
+			return
+		case "rollback":
+			runRollback(os.Args[2:])
+			return
+		case "webhook":
+			runWebhook(os.Args[2:])
+			return
+		case "report":
+			runReport(os.Args[2:])
+			return
+		case "preflight":
+			runPreflight(os.Args[2:])
+			return
+		case "controller":
+			runController(os.Args[2:])
+			return
+		}
+	}
+	parseFlags(os.Args[1:])
 	logger.Info("Starting controller for adding node labels")
-	k8sClient, err := k8s_utils.Getk8sClientSet()
-	if err != nil {
-		logger.Fatal("Failed to kubernetes create client set", zap.Error(err))
+	serveMetrics()
+	shutdownTracing := mustSetUpTracing(context.Background())
+	defer shutdownTracing()
+	nodeName := *nodeNameFlag
+	if nodeName == "" && *selectorFlag == "" {
+		logger.Fatal("No node to label, set -node, the NODE_NAME environment variable or -selector")
+	}
+	ctx, span := nodeupdater.StartSpan(context.Background(), "LabelNode", attribute.String("node", nodeName), attribute.String("selector", *selectorFlag))
+	defer span.End()
+
+	k8sClient := newKubernetesClient()
+	if *selectorFlag != "" {
+		c := newNodeLabelUpdater(ctx, &k8sClient)
+		var stopRecorder func()
+		c.Recorder, stopRecorder = newEventRecorder(k8sClient.Clientset)
+		defer stopRecorder()
+		if err := reconcileSelectedNodes(ctx, c, *selectorFlag); err != nil {
+			nodeupdater.EndSpan(span, err)
+			shutdownTracing()
+			stopRecorder()
+			logger.Fatal("error in updating labels for nodes", zap.String("selector", *selectorFlag), zap.Error(err))
+		}
+		saveInstanceCache(&k8sClient, c.InstanceCache)
+		return
 	}
-	nodeName := os.Getenv("NODE_NAME")
 
 	// Do multiple retries to get node details.
 	logger.Info("Getting node details")
 	var node *v1.Node
-	errRetry := nodeupdater.ErrorRetry(logger, func() (error, bool) {
-		node, err = k8sClient.Clientset.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	var err error
+	getCtx, getSpan := nodeupdater.StartSpan(ctx, "GetNode")
+	errRetry := nodeupdater.ErrorRetryWithContext(getCtx, logger, func() (error, bool) {
+		node, err = k8sClient.Clientset.CoreV1().Nodes().Get(getCtx, nodeName, metav1.GetOptions{})
 		if errors.IsNotFound(err) {
 			runtimeu.HandleError(fmt.Errorf("node '%s' no longer exist in the cluster", nodeName))
 			return err, true // Skip retry if node doesnot exist.
@@ -84,26 +232,189 @@ func main() {
 		}
 		return nil, true
 	})
+	nodeupdater.EndSpan(getSpan, errRetry)
 	if errRetry != nil || node == nil {
+		nodeupdater.EndSpan(span, errRetry)
+		shutdownTracing()
 		logger.Fatal("Failed to get node details. Error :", zap.Error(errRetry))
 	}
 
-	if nodeupdater.CheckIfRequiredLabelsPresent(node.ObjectMeta.Labels) {
-		logger.Info("Required labels already present on the worker node")
-		return
+	recorder, stopRecorder := newEventRecorder(k8sClient.Clientset)
+	defer stopRecorder()
+	c, err := nodeupdater.ReconcileNode(ctx, node, func() (*nodeupdater.VpcNodeLabelUpdater, error) {
+		c, err := setUpNodeLabelUpdater(ctx, &k8sClient)
+		if err != nil {
+			return nil, err
+		}
+		c.Recorder = recorder
+		return c, nil
+	}, logger)
+	if err != nil {
+		// logger.Fatal exits without running the deferred calls, flush the failed trace and the conflict events first.
+		nodeupdater.EndSpan(span, err)
+		shutdownTracing()
+		stopRecorder()
+		logger.Fatal("error in updating labels for node", zap.Reflect("workerNodeName", nodeName), zap.Error(err))
+	}
+	if c != nil {
+		saveInstanceCache(&k8sClient, c.InstanceCache)
 	}
+}
 
-	var secretConfig *nodeupdater.StorageSecretConfig
-	if secretConfig, err = nodeupdater.ReadSecretConfiguration(&k8sClient, logger); err != nil {
+// reconcileSelectedNodes labels or verifies the labels of the nodes matching the selector, one after the other.
+// The nodes are looked up in an instance cache, of one minute unless -instance-cache-ttl is set.
+func reconcileSelectedNodes(ctx context.Context, updater *nodeupdater.VpcNodeLabelUpdater, selector string) error {
+	nodes, err := updater.K8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return err
+	}
+	if updater.InstanceCache == nil {
+		updater.InstanceCache = nodeupdater.NewInstanceCache(time.Minute)
+	}
+	failed := 0
+	for i := range nodes.Items {
+		nodeUpdater := *updater
+		nodeUpdater.Node = &nodes.Items[i]
+		if err = nodeUpdater.ReconcileNodeLabel(ctx, nodeUpdater.Node.Name); err != nil {
+			logger.Error("error in updating labels for node", zap.String("workerNodeName", nodeUpdater.Node.Name), zap.Error(err))
+			failed++
+		}
+	}
+	logger.Info("Reconciled the labels of the selected nodes", zap.String("selector", selector), zap.Int("nodes", len(nodes.Items)), zap.Int("failed", failed))
+	if failed > 0 {
+		return fmt.Errorf("failed to update the labels of %d of %d nodes", failed, len(nodes.Items))
+	}
+	return nil
+}
+
+// newNodeLabelUpdater parses the labeling flags and reads the secret configuration into an updater
+// without node and event recorder.
+func newNodeLabelUpdater(ctx context.Context, k8sClient *k8s_utils.KubernetesClient) *nodeupdater.VpcNodeLabelUpdater {
+	updater, err := setUpNodeLabelUpdater(ctx, k8sClient)
+	if err != nil {
 		logger.Fatal("Failed to read secret configuration", zap.Error(err))
 	}
-	c := &nodeupdater.VpcNodeLabelUpdater{
-		Node:                node,
+	return updater
+}
+
+// setUpNodeLabelUpdater is newNodeLabelUpdater returning the errors of the secret configuration. Invalid flags
+// are still fatal.
+func setUpNodeLabelUpdater(ctx context.Context, k8sClient *k8s_utils.KubernetesClient) (*nodeupdater.VpcNodeLabelUpdater, error) {
+	policy, err := nodeupdater.ParseDriftPolicy(*driftPolicy)
+	if err != nil {
+		logger.Fatal("Invalid drift policy", zap.Error(err))
+	}
+	workerIDMode, err := nodeupdater.ParseLegacyLabelMode(*workerIDLabel)
+	if err != nil {
+		logger.Fatal("Invalid legacy label mode", zap.Error(err))
+	}
+	conflictPolicies, err := nodeupdater.ParseLabelConflictPolicies(*labelConflictPolicy, *labelConflictPolicies)
+	if err != nil {
+		logger.Fatal("Invalid label conflict policy", zap.Error(err))
+	}
+	failureDomainPolicy := nodeupdater.FailureDomainPolicy{
+		Mode:              *failureDomainLabels,
+		KeepFromVersion:   *failureDomainKeepFrom,
+		RemoveFromVersion: *failureDomainRemoveFrom,
+	}
+	if err = failureDomainPolicy.Validate(); err != nil {
+		logger.Fatal("Invalid failure-domain label policy", zap.Error(err))
+	}
+	snapshotStore, err := nodeupdater.NewSnapshotStore(*labelSnapshotStore, k8sClient.Clientset, k8sClient.Namespace, *labelSnapshotConfigMap)
+	if err != nil {
+		logger.Fatal("Invalid label snapshot store", zap.Error(err))
+	}
+
+	var secretConfig *nodeupdater.StorageSecretConfig
+	if secretConfig, err = nodeupdater.ReadSecretConfigurationFrom(ctx, secretConfigSource(k8sClient), logger); err != nil {
+		return nil, err
+	}
+	schemaOptions := nodeupdater.LabelSchemaOptions{
+		WorkerIDLabel:       workerIDMode,
+		FailureDomainLabels: nodeupdater.ResolveFailureDomainLabelMode(k8sClient.Clientset.Discovery(), failureDomainPolicy, logger),
+	}
+	updater := &nodeupdater.VpcNodeLabelUpdater{
 		K8sClient:           k8sClient.Clientset,
 		Logger:              logger,
 		StorageSecretConfig: secretConfig,
+		InstanceCache:       loadInstanceCache(k8sClient),
+		DriftPolicy:         policy,
+		LabelSchemaOptions:  schemaOptions,
+		ConflictPolicies:    conflictPolicies,
+		SnapshotStore:       snapshotStore,
+		InstanceListFilter:  nodeupdater.InstanceListFilter{VPCID: *vpcIDFilter, ResourceGroupID: *resourceGroupIDFilter},
 	}
-	if _, err := c.UpdateNodeLabel(context.TODO(), nodeName); err != nil {
-		logger.Fatal("error in updating labels for node", zap.Reflect("workerNodeName", nodeName), zap.Error(err))
+	if *discoverInstanceFilter && (*vpcIDFilter == "" || *resourceGroupIDFilter == "") {
+		discovered, err := updater.DiscoverInstanceListFilter(ctx)
+		if err != nil {
+			logger.Warn("Failed to discover instance list filter, listing instances without it", zap.Error(err))
+		} else {
+			updater.InstanceListFilter = updater.InstanceListFilter.Merge(discovered)
+		}
+	}
+	if *credentialsGroupFilter {
+		updater.InstanceListFilter = updater.InstanceListFilter.Merge(nodeupdater.InstanceListFilter{ResourceGroupID: secretConfig.ResourceGroupID})
+	}
+	if filter := updater.InstanceListFilter; filter != (nodeupdater.InstanceListFilter{}) {
+		logger.Info("Filtering the instance list", zap.String("vpcID", filter.VPCID), zap.String("resourceGroupID", filter.ResourceGroupID))
+	}
+	return updater, nil
+}
+
+// serveMetrics serves the prometheus metrics and the log level endpoint in the background if enabled.
+func serveMetrics() {
+	if *metricsBindAddress != "" {
+		serveHandler("Metrics", *metricsBindAddress, "/metrics", promhttp.Handler())
+	}
+	if *logLevelBindAddress != "" {
+		// GET returns the log level, PUT with {"level":"debug"} changes it.
+		serveHandler("Log level", *logLevelBindAddress, "/loglevel", logLevel)
+	}
+}
+
+// serveHandler serves handler on the path of address in the background.
+func serveHandler(name, address, path string, handler http.Handler) {
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+	server := &http.Server{Addr: address, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error(name+" server stopped", zap.Error(err))
+		}
+	}()
+}
+
+// newEventRecorder returns a recorder for node events and a function flushing the pending events.
+func newEventRecorder(k8sClient kubernetes.Interface) (record.EventRecorder, func()) {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k8sClient.CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "vpc-node-label-updater"})
+	return recorder, broadcaster.Shutdown
+}
+
+// loadInstanceCache creates the instance cache if enabled, warm-starting it from the shared configmap.
+func loadInstanceCache(k8sClient *k8s_utils.KubernetesClient) *nodeupdater.InstanceCache {
+	if *instanceCacheTTL <= 0 {
+		return nil
+	}
+	instanceCache := nodeupdater.NewInstanceCache(*instanceCacheTTL)
+	if *instanceCacheConfigMap == "" {
+		return instanceCache
+	}
+	if err := instanceCache.LoadFromConfigMap(context.TODO(), k8sClient.Clientset, k8sClient.Namespace, *instanceCacheConfigMap); err != nil {
+		logger.Warn("Failed to load instance cache from configmap, starting with an empty cache", zap.String("configmap", *instanceCacheConfigMap), zap.Error(err))
+	}
+	return instanceCache
+}
+
+// saveInstanceCache shares a freshly filled instance cache with the pods started after this one.
+func saveInstanceCache(k8sClient *k8s_utils.KubernetesClient, instanceCache *nodeupdater.InstanceCache) {
+	if instanceCache == nil || *instanceCacheConfigMap == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := instanceCache.SaveToConfigMap(ctx, k8sClient.Clientset, k8sClient.Namespace, *instanceCacheConfigMap, logger); err != nil {
+		logger.Warn("Failed to save instance cache to configmap", zap.String("configmap", *instanceCacheConfigMap), zap.Error(err))
 	}
 }
@@ -0,0 +1,74 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package main ...
+package main
+
+import (
+	"context"
+	"flag"
+
+	nodeupdater "github.com/IBM/vpc-node-label-updater/pkg/nodeupdater"
+	"go.uber.org/zap"
+	"k8s.io/client-go/dynamic"
+)
+
+var nodeLabelPolicies = flag.Bool("node-label-policies", false, "With the controller and report subcommands, apply the labels and annotations of the NodeLabelPolicy resources. Requires deploy/crd.yaml")
+
+// newDynamicClient returns a client of the NodeLabelPolicy resources.
+func newDynamicClient() dynamic.Interface {
+	config, err := restConfig()
+	if err != nil {
+		logger.Fatal("Failed to get cluster config", zap.Error(err))
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		logger.Fatal("Failed to create dynamic client", zap.Error(err))
+	}
+	return client
+}
+
+// newPolicyWatcher returns a watcher of the node label policies applied by the updater, or nil if disabled.
+func newPolicyWatcher(updater *nodeupdater.VpcNodeLabelUpdater) *nodeupdater.PolicyWatcher {
+	if !*nodeLabelPolicies {
+		return nil
+	}
+	watcher := nodeupdater.NewPolicyWatcher(newDynamicClient(), logger, *controllerResyncPeriod)
+	updater.Policies = watcher.PolicySet
+	return watcher
+}
+
+// attachPolicyWatcher reconciles all the nodes of the controller when the policies change, and computes the
+// status of the policies over all the nodes.
+func attachPolicyWatcher(watcher *nodeupdater.PolicyWatcher, controller *nodeupdater.Controller) {
+	if watcher == nil {
+		return
+	}
+	watcher.Nodes = controller.AllNodes
+	watcher.OnChange = controller.EnqueueAll
+}
+
+// loadPolicies lists the node label policies once for the updater, if enabled.
+func loadPolicies(ctx context.Context, updater *nodeupdater.VpcNodeLabelUpdater) {
+	if !*nodeLabelPolicies {
+		return
+	}
+	policies, err := nodeupdater.ListPolicies(ctx, newDynamicClient())
+	if err != nil {
+		logger.Fatal("Failed to list node label policies", zap.Error(err))
+	}
+	updater.Policies = func() *nodeupdater.PolicySet { return policies }
+}
@@ -0,0 +1,71 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package main ...
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+
+	nodeupdater "github.com/IBM/vpc-node-label-updater/pkg/nodeupdater"
+	"go.uber.org/zap"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// runPreflight checks everything labeling the node depends on and prints a pass/fail report.
+// It exits with status 1 if any check failed.
+func runPreflight(args []string) {
+	flags := addClientFlags(flag.NewFlagSet("preflight", flag.ExitOnError))
+	node := flags.String("node", os.Getenv("NODE_NAME"), "Name of the node resolved to a VPC instance. Defaults to the NODE_NAME environment variable")
+	output := flags.String("output", outputText, "Report format: text or json")
+	_ = flags.Parse(args) // #nosec G104: flag.ExitOnError exits on parse errors.
+	if *output != outputText && *output != outputJSON {
+		logger.Fatal("Invalid output format", zap.String("output", *output))
+	}
+	logToStderr()
+
+	k8sClient := newKubernetesClient()
+	report := nodeupdater.RunPreflight(context.TODO(), &k8sClient, secretConfigSource(&k8sClient), *node, logger)
+	if err := printReport(report, *output); err != nil {
+		logger.Fatal("Failed to print preflight report", zap.Error(err))
+	}
+	if !report.Passed {
+		os.Exit(1)
+	}
+}
+
+// textReport is a report which can be written as text.
+type textReport interface {
+	WriteText(w io.Writer) error
+}
+
+// printReport writes the report to stdout as indented JSON, or as text.
+func printReport(report textReport, output string) error {
+	if output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	return report.WriteText(os.Stdout)
+}
@@ -0,0 +1,53 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package main ...
+package main
+
+import (
+	"context"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+// runReport resolves every node against VPC and prints whether its labels are consistent with it.
+// It exits with status 1 if any node has missing or drifted labels, or no instance.
+func runReport(args []string) {
+	flags := subcommandFlagSet("report")
+	selector := flags.String("selector", "", "Label selector of the nodes to report on. Empty selects all nodes")
+	output := flags.String("output", outputText, "Report format: text or json")
+	_ = flags.Parse(args) // #nosec G104: flag.ExitOnError exits on parse errors.
+	logToStderr()
+	if *output != outputText && *output != outputJSON {
+		logger.Fatal("Invalid output format", zap.String("output", *output))
+	}
+
+	k8sClient := newKubernetesClient()
+	ctx := context.Background()
+	updater := newNodeLabelUpdater(ctx, &k8sClient)
+	loadPolicies(ctx, updater)
+	report, err := updater.BuildClusterReport(ctx, *selector)
+	if err != nil {
+		logger.Fatal("Failed to build the node label report", zap.Error(err))
+	}
+	if err = printReport(report, *output); err != nil {
+		logger.Fatal("Failed to print node label report", zap.Error(err))
+	}
+	if !report.Consistent() {
+		os.Exit(1)
+	}
+}
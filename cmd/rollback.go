@@ -0,0 +1,50 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package main ...
+package main
+
+import (
+	"context"
+	"flag"
+
+	nodeupdater "github.com/IBM/vpc-node-label-updater/pkg/nodeupdater"
+	"go.uber.org/zap"
+)
+
+// runRollback restores the labels of a node, or of the selected nodes, from the snapshots saved before the updater changed them.
+func runRollback(args []string) {
+	flags := addClientFlags(flag.NewFlagSet("rollback", flag.ExitOnError))
+	node := flags.String("node", "", "Name of the node to roll back")
+	selector := flags.String("selector", "", "Label selector of the nodes to roll back, when -node is not set. Empty selects all nodes")
+	store := flags.String("label-snapshot-store", nodeupdater.SnapshotStoreAnnotation, "Where the label snapshots were saved: annotation or configmap")
+	configMap := flags.String("label-snapshot-configmap", "vpc-node-label-snapshots", "With -label-snapshot-store=configmap, name of the configmap in the pod namespace holding the label snapshots")
+	dryRun := flags.Bool("dry-run", false, "Only log the labels the nodes would be rolled back to")
+	_ = flags.Parse(args) // #nosec G104: flag.ExitOnError exits on parse errors.
+
+	logger.Info("Rolling back node labels", zap.String("node", *node), zap.String("selector", *selector), zap.String("store", *store), zap.Bool("dryRun", *dryRun))
+	k8sClient := newKubernetesClient()
+	snapshotStore, err := nodeupdater.NewSnapshotStore(*store, k8sClient.Clientset, k8sClient.Namespace, *configMap)
+	if err != nil || snapshotStore == nil {
+		logger.Fatal("Invalid label snapshot store", zap.String("store", *store), zap.Error(err))
+	}
+	options := nodeupdater.RollbackOptions{Node: *node, Selector: *selector, DryRun: *dryRun}
+	rolledBack, err := nodeupdater.RollbackNodes(context.TODO(), k8sClient.Clientset, snapshotStore, options, logger)
+	if err != nil {
+		logger.Fatal("Failed to roll back node labels", zap.Int("rolledBackNodes", rolledBack), zap.Error(err))
+	}
+	logger.Info("Rolled back node labels", zap.Int("rolledBackNodes", rolledBack))
+}
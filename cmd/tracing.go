@@ -0,0 +1,103 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package main ...
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.uber.org/zap"
+)
+
+const (
+	tracingExporterNone   = "none"
+	tracingExporterOTLP   = "otlp"
+	tracingExporterStdout = "stdout"
+)
+
+var (
+	tracingExporter    = flag.String("tracing-exporter", tracingExporterNone, "Where OpenTelemetry spans are exported: none, otlp or stdout. The otlp exporter is configured with the standard OTEL_EXPORTER_OTLP_* environment variables")
+	tracingSampleRatio = flag.Float64("tracing-sample-ratio", 1, "Fraction of the traces sampled, between 0 and 1")
+)
+
+// setUpTracing installs the global tracer provider and trace context propagator, and returns a function
+// flushing the pending spans.
+func setUpTracing(ctx context.Context) (func(), error) {
+	// Propagate the trace context to VPC even without exporter, for traces started by callers.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch *tracingExporter {
+	case tracingExporterNone:
+		return func() {}, nil
+	case tracingExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	case tracingExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	default:
+		return nil, fmt.Errorf("invalid tracing exporter %q, must be one of %q, %q, %q", *tracingExporter, tracingExporterNone, tracingExporterOTLP, tracingExporterStdout)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Schemaless, as merging attributes of different semantic convention versions fails.
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName("vpc-node-label-updater"),
+		semconv.ServiceInstanceID(os.Getenv("NODE_NAME")+os.Getenv("POD_NAME")),
+	))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(*tracingSampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("Failed to export spans", zap.Error(err))
+	}))
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			logger.Warn("Failed to flush spans", zap.Error(err))
+		}
+	}, nil
+}
+
+// mustSetUpTracing sets up tracing and exits on invalid tracing flags.
+func mustSetUpTracing(ctx context.Context) func() {
+	shutdown, err := setUpTracing(ctx)
+	if err != nil {
+		logger.Fatal("Failed to set up tracing", zap.Error(err))
+	}
+	return shutdown
+}
@@ -0,0 +1,119 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package main ...
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	nodeupdater "github.com/IBM/vpc-node-label-updater/pkg/nodeupdater"
+	"go.uber.org/zap"
+)
+
+// runWebhook serves the admission webhooks protecting the managed node labels, and optionally adding them
+// to nodes at registration.
+func runWebhook(args []string) {
+	flags := subcommandFlagSet("webhook")
+	bindAddress := flags.String("webhook-bind-address", ":9443", "Address on which the admission webhooks are served over TLS")
+	certFile := flags.String("tls-cert-file", "/etc/webhook/certs/tls.crt", "Serving certificate of the webhooks. Read again when it changes, e.g. when renewed by cert-manager")
+	keyFile := flags.String("tls-key-file", "/etc/webhook/certs/tls.key", "Private key of the serving certificate")
+	allowedUsers := flags.String("webhook-allowed-users", "system:serviceaccount:kube-system:node-sa,system:serviceaccount:kube-system:vpc-node-label-controller", "Comma separated list of the users allowed to change the managed node labels. Defaults to the service accounts of the updater")
+	allowedGroups := flags.String("webhook-allowed-groups", "", "Comma separated list of the groups allowed to change the managed node labels")
+	labelNodes := flags.Bool("webhook-label-nodes", false, "Also serve the mutating webhook adding the labels to nodes at registration on /mutate-node")
+	lookupTimeout := flags.Duration("webhook-lookup-timeout", 8*time.Second, "With -webhook-label-nodes, how long the lookup of a registering node may take before it is admitted unlabeled and labeled asynchronously. Must be shorter than the timeout of the webhook configuration")
+	maxLookups := flags.Int("webhook-max-lookups", 10, "With -webhook-label-nodes, how many lookups of registering nodes may run at once. Further nodes are admitted unlabeled and labeled asynchronously")
+	failurePolicyFlag := flags.String("webhook-failure-policy", string(nodeupdater.WebhookFailureIgnore), "Whether node updates the webhook fails to review are admitted (ignore) or rejected (fail). Match the failurePolicy of the webhook configuration")
+	_ = flags.Parse(args) // #nosec G104: flag.ExitOnError exits on parse errors.
+	// Set up the logger with the logging flags parsed above.
+	parseFlags(nil)
+
+	failurePolicy, err := nodeupdater.ParseWebhookFailurePolicy(*failurePolicyFlag)
+	if err != nil {
+		logger.Fatal("Invalid webhook failure policy", zap.Error(err))
+	}
+	certificates, err := nodeupdater.NewCertificateReloader(*certFile, *keyFile)
+	if err != nil {
+		logger.Fatal("Failed to load webhook serving certificate", zap.Error(err))
+	}
+	serveMetrics()
+
+	mux := http.NewServeMux()
+	mux.Handle("/validate-node", &nodeupdater.LabelProtectionWebhook{
+		Logger:        logger,
+		AllowedUsers:  splitList(*allowedUsers),
+		AllowedGroups: splitList(*allowedGroups),
+		FailurePolicy: failurePolicy,
+	})
+	if *labelNodes {
+		mux.Handle("/mutate-node", &nodeupdater.NodeLabelingWebhook{
+			Updater:              newLabelingWebhookUpdater(),
+			LookupTimeout:        *lookupTimeout,
+			MaxConcurrentLookups: *maxLookups,
+			FailurePolicy:        failurePolicy,
+		})
+	}
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok")) // #nosec G104: nothing to do if the probe went away.
+	})
+	server := &http.Server{
+		Addr:              *bindAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: certificates.GetCertificate},
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx) // #nosec G104: the process exits anyway.
+	}()
+	logger.Info("Serving admission webhooks", zap.String("address", *bindAddress), zap.String("failurePolicy", string(failurePolicy)))
+	if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+		logger.Fatal("Admission webhook server stopped", zap.Error(err))
+	}
+}
+
+// newLabelingWebhookUpdater returns the updater looking up registering nodes. Their lookups are served from an
+// instance cache, of one minute unless -instance-cache-ttl is set, as many nodes register at the same time.
+func newLabelingWebhookUpdater() *nodeupdater.VpcNodeLabelUpdater {
+	k8sClient := newKubernetesClient()
+	updater := newNodeLabelUpdater(context.Background(), &k8sClient)
+	if updater.InstanceCache == nil {
+		updater.InstanceCache = nodeupdater.NewInstanceCache(time.Minute)
+	}
+	return updater
+}
+
+// splitList splits a comma separated list, ignoring empty items.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
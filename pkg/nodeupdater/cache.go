@@ -0,0 +1,268 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	instanceCacheDataKey = "instances.json"
+	// maxInstanceCacheSize keeps the persisted cache safely below the 1MiB ConfigMap limit.
+	maxInstanceCacheSize = 900 * 1024
+)
+
+// InstanceCache holds the VPC instances of the account keyed by instance ID, name and
+// primary IPv4 address, so that a single list call can serve the lookups of many nodes.
+type InstanceCache struct {
+	mutex     sync.RWMutex
+	ttl       time.Duration
+	fetchedAt time.Time
+	modified  bool
+	byID      map[string]*Instance
+	byName    map[string]*Instance
+	byIP      map[string]*Instance
+}
+
+// persistedInstanceCache is the format in which the cache is stored in a ConfigMap.
+type persistedInstanceCache struct {
+	FetchedAt time.Time   `json:"fetchedAt"`
+	Instances []*Instance `json:"instances"`
+}
+
+// NewInstanceCache returns an empty cache whose content expires ttl after it is filled.
+func NewInstanceCache(ttl time.Duration) *InstanceCache {
+	return &InstanceCache{
+		ttl:    ttl,
+		byID:   map[string]*Instance{},
+		byName: map[string]*Instance{},
+		byIP:   map[string]*Instance{},
+	}
+}
+
+// Fill replaces the content of the cache with the given instance list.
+func (ic *InstanceCache) Fill(instances []*Instance) {
+	ic.mutex.Lock()
+	defer ic.mutex.Unlock()
+	ic.fill(instances, time.Now())
+	ic.modified = true
+}
+
+func (ic *InstanceCache) fill(instances []*Instance, fetchedAt time.Time) {
+	ic.byID = make(map[string]*Instance, len(instances))
+	ic.byName = make(map[string]*Instance, len(instances))
+	ic.byIP = make(map[string]*Instance, len(instances))
+	for _, instance := range instances {
+		if instance == nil {
+			continue
+		}
+		ic.byID[instance.ID] = instance
+		ic.byName[instance.Name] = instance
+		if ip := primaryIPv4Address(instance); ip != "" {
+			ic.byIP[ip] = instance
+		}
+	}
+	ic.fetchedAt = fetchedAt
+}
+
+// Expired returns true if the cache was never filled or its content is older than the TTL.
+func (ic *InstanceCache) Expired() bool {
+	ic.mutex.RLock()
+	defer ic.mutex.RUnlock()
+	return ic.expired()
+}
+
+func (ic *InstanceCache) expired() bool {
+	return ic.fetchedAt.IsZero() || time.Since(ic.fetchedAt) > ic.ttl
+}
+
+// Lookup returns the cached instance of the worker node, looked up by primary IPv4 address
+// if the worker node name is an IP and by instance name otherwise.
+func (ic *InstanceCache) Lookup(workerNodeName string) (*Instance, bool) {
+	ic.mutex.RLock()
+	defer ic.mutex.RUnlock()
+	if ic.expired() {
+		return nil, false
+	}
+	if net.ParseIP(workerNodeName) != nil {
+		instance, ok := ic.byIP[workerNodeName]
+		return instance, ok
+	}
+	instance, ok := ic.byName[workerNodeName]
+	return instance, ok
+}
+
+// GetByID returns the cached instance with the given instance ID.
+func (ic *InstanceCache) GetByID(instanceID string) (*Instance, bool) {
+	ic.mutex.RLock()
+	defer ic.mutex.RUnlock()
+	if ic.expired() {
+		return nil, false
+	}
+	instance, ok := ic.byID[instanceID]
+	return instance, ok
+}
+
+// Invalidate drops the whole content of the cache, so that the next lookup lists the instances again.
+func (ic *InstanceCache) Invalidate() {
+	ic.mutex.Lock()
+	defer ic.mutex.Unlock()
+	ic.fill(nil, time.Time{})
+}
+
+// InvalidateInstance drops a single instance from the cache, e.g. after VPC reported it as not found.
+func (ic *InstanceCache) InvalidateInstance(instanceID string) {
+	ic.mutex.Lock()
+	defer ic.mutex.Unlock()
+	instance, ok := ic.byID[instanceID]
+	if !ok {
+		return
+	}
+	delete(ic.byID, instanceID)
+	if ic.byName[instance.Name] == instance {
+		delete(ic.byName, instance.Name)
+	}
+	if ip := primaryIPv4Address(instance); ip != "" && ic.byIP[ip] == instance {
+		delete(ic.byIP, ip)
+	}
+	ic.modified = true
+}
+
+// Instances returns all the cached instances.
+func (ic *InstanceCache) Instances() []*Instance {
+	ic.mutex.RLock()
+	defer ic.mutex.RUnlock()
+	instances := make([]*Instance, 0, len(ic.byID))
+	for _, instance := range ic.byID {
+		instances = append(instances, instance)
+	}
+	return instances
+}
+
+// LoadFromConfigMap warm-starts the cache from a ConfigMap written by SaveToConfigMap.
+// A missing ConfigMap is not an error, and expired content is ignored.
+func (ic *InstanceCache) LoadFromConfigMap(ctx context.Context, k8sClient kubernetes.Interface, namespace, name string) error {
+	cm, err := k8sClient.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data, ok := cm.Data[instanceCacheDataKey]
+	if !ok {
+		return nil
+	}
+	var persisted persistedInstanceCache
+	if err := json.Unmarshal([]byte(data), &persisted); err != nil {
+		return fmt.Errorf("failed to unmarshal instance cache from configmap %s/%s: %v", namespace, name, err)
+	}
+
+	ic.mutex.Lock()
+	defer ic.mutex.Unlock()
+	if time.Since(persisted.FetchedAt) > ic.ttl {
+		return nil
+	}
+	ic.fill(persisted.Instances, persisted.FetchedAt)
+	ic.modified = false
+	return nil
+}
+
+// SaveToConfigMap persists the cache in a ConfigMap so that new pods can warm-start from it.
+// Nothing is written unless the cache was filled since it was created or loaded.
+func (ic *InstanceCache) SaveToConfigMap(ctx context.Context, k8sClient kubernetes.Interface, namespace, name string, logger *zap.Logger) error {
+	ic.mutex.Lock()
+	if !ic.modified || ic.expired() {
+		ic.mutex.Unlock()
+		return nil
+	}
+	persisted := persistedInstanceCache{FetchedAt: ic.fetchedAt}
+	for _, instance := range ic.byID {
+		persisted.Instances = append(persisted.Instances, compactInstance(instance))
+	}
+	ic.modified = false
+	ic.mutex.Unlock()
+
+	data, err := json.Marshal(persisted)
+	if err != nil {
+		return err
+	}
+	if len(data) > maxInstanceCacheSize {
+		logger.Warn("Instance cache is too large to be stored in a configmap, skipping", zap.Int("instanceCount", len(persisted.Instances)), zap.Int("size", len(data)))
+		return nil
+	}
+
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data:       map[string]string{instanceCacheDataKey: string(data)},
+	}
+	_, err = k8sClient.CoreV1().ConfigMaps(namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	if errors.IsNotFound(err) {
+		_, err = k8sClient.CoreV1().ConfigMaps(namespace).Create(ctx, cm, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+	logger.Info("Saved instance cache", zap.String("configmap", namespace+"/"+name), zap.Int("instanceCount", len(persisted.Instances)))
+	return nil
+}
+
+// compactInstance keeps only the instance fields needed for node lookups and policy templates, so that
+// templates render the same from a fresh listing and from a persisted cache.
+func compactInstance(instance *Instance) *Instance {
+	if instance == nil {
+		return nil
+	}
+	compact := &Instance{
+		ID:            instance.ID,
+		Name:          instance.Name,
+		CRN:           instance.CRN,
+		Status:        instance.Status,
+		Memory:        instance.Memory,
+		Vcpu:          instance.Vcpu,
+		Zone:          instance.Zone,
+		Profile:       instance.Profile,
+		Vpc:           instance.Vpc,
+		ResourceGroup: instance.ResourceGroup,
+	}
+	if instance.Image != nil {
+		compact.Image = &Image{ID: instance.Image.ID, Name: instance.Image.Name}
+	}
+	if ip := primaryIPv4Address(instance); ip != "" {
+		compact.PrimaryNetworkInterface = &NetworkInterface{PrimaryIpv4Address: ip}
+	}
+	return compact
+}
+
+func primaryIPv4Address(instance *Instance) string {
+	if instance.PrimaryNetworkInterface == nil {
+		return ""
+	}
+	return instance.PrimaryNetworkInterface.PrimaryIpv4Address
+}
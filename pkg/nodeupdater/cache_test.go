@@ -0,0 +1,192 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newFakeRiaasServer serves the given instances as a paginated instance list, one instance per page, filtered by
+// VPC, and by ID.
+func newFakeRiaasServer(t *testing.T, instances []*Instance, requests *int) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if instanceID, ok := strings.CutPrefix(r.URL.Path, "/v1/instances/"); ok {
+			for _, instance := range instances {
+				if instance.ID == instanceID {
+					_ = json.NewEncoder(w).Encode(instance) // #nosec G104: test server
+					return
+				}
+			}
+			http.NotFound(w, r)
+			return
+		}
+		start := 0
+		if r.URL.Query().Get("start") != "" {
+			start = len(r.URL.Query().Get("start"))
+		}
+		listed := instances
+		if vpcID := r.URL.Query().Get("vpc.id"); vpcID != "" {
+			listed = nil
+			for _, instance := range instances {
+				if instance.Vpc != nil && instance.Vpc.ID == vpcID {
+					listed = append(listed, instance)
+				}
+			}
+		}
+		list := InstanceList{Instances: listed[min(start, len(listed)):min(start+1, len(listed))]}
+		if start+1 < len(listed) {
+			q := r.URL.Query()
+			q.Set("start", q.Get("start")+"x")
+			list.Next = &HReference{Href: server.URL + r.URL.Path + "?" + q.Encode()}
+		}
+		_ = json.NewEncoder(w).Encode(list) // #nosec G104: test server
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func testInstances() []*Instance {
+	return []*Instance{
+		{ID: "instance-1", Name: "worker-1", Zone: &Zone{Name: "us-south-1"}, PrimaryNetworkInterface: &NetworkInterface{PrimaryIpv4Address: "10.0.0.1"}},
+		{ID: "instance-2", Name: "worker-2", Zone: &Zone{Name: "us-south-2"}, PrimaryNetworkInterface: &NetworkInterface{PrimaryIpv4Address: "10.0.0.2"}},
+		{ID: "instance-3", Name: "worker-3", Zone: &Zone{Name: "us-south-3"}, PrimaryNetworkInterface: &NetworkInterface{PrimaryIpv4Address: "10.0.0.3"}},
+	}
+}
+
+func TestInstanceCacheLookup(t *testing.T) {
+	ic := NewInstanceCache(time.Minute)
+	_, ok := ic.Lookup("worker-1")
+	assert.False(t, ok)
+	assert.True(t, ic.Expired())
+
+	ic.Fill(testInstances())
+	instance, ok := ic.Lookup("worker-2")
+	assert.True(t, ok)
+	assert.Equal(t, "instance-2", instance.ID)
+	instance, ok = ic.Lookup("10.0.0.3")
+	assert.True(t, ok)
+	assert.Equal(t, "instance-3", instance.ID)
+	_, ok = ic.GetByID("instance-1")
+	assert.True(t, ok)
+
+	ic.InvalidateInstance("instance-1")
+	_, ok = ic.Lookup("worker-1")
+	assert.False(t, ok)
+	_, ok = ic.Lookup("10.0.0.1")
+	assert.False(t, ok)
+
+	ic.Invalidate()
+	assert.True(t, ic.Expired())
+	_, ok = ic.Lookup("worker-2")
+	assert.False(t, ok)
+}
+
+func TestGetInstanceInvalidatesCache(t *testing.T) {
+	requests := 0
+	server := newFakeRiaasServer(t, testInstances()[1:], &requests)
+
+	updater := initNodeLabelUpdater(t)
+	updater.StorageSecretConfig.RiaasEndpointURL, _ = url.Parse(server.URL + "/v1/instances?generation=2")
+	updater.InstanceCache = NewInstanceCache(time.Minute)
+	updater.InstanceCache.Fill(testInstances())
+
+	// An instance VPC reports as not found is dropped from the cache, the others are kept.
+	_, err := updater.getInstance(context.TODO(), "instance-1")
+	assert.ErrorIs(t, err, errInstanceNotFound)
+	_, ok := updater.InstanceCache.GetByID("instance-1")
+	assert.False(t, ok)
+	_, err = updater.getInstance(context.TODO(), "instance-2")
+	assert.Nil(t, err)
+	_, ok = updater.InstanceCache.GetByID("instance-2")
+	assert.True(t, ok)
+}
+
+func TestInstanceCacheTTL(t *testing.T) {
+	ic := NewInstanceCache(time.Millisecond)
+	ic.Fill(testInstances())
+	time.Sleep(5 * time.Millisecond)
+	_, ok := ic.Lookup("worker-1")
+	assert.False(t, ok)
+}
+
+func TestGetWorkerDetailsFromCache(t *testing.T) {
+	requests := 0
+	server := newFakeRiaasServer(t, testInstances(), &requests)
+
+	updater := initNodeLabelUpdater(t)
+	updater.StorageSecretConfig.RiaasEndpointURL, _ = url.Parse(server.URL + "/v1/instances?generation=2")
+	updater.InstanceCache = NewInstanceCache(time.Minute)
+
+	// The first lookup lists all pages, the following ones are served from the cache.
+	nodeInfo, err := updater.GetWorkerDetails(context.TODO(), "worker-3")
+	assert.Nil(t, err)
+	assert.Equal(t, &NodeInfo{InstanceID: "instance-3", Region: "us-south", Zone: "us-south-3", Instance: testInstances()[2]}, nodeInfo)
+	assert.Equal(t, 3, requests)
+
+	nodeInfo, err = updater.GetWorkerDetails(context.TODO(), "10.0.0.1")
+	assert.Nil(t, err)
+	assert.Equal(t, "instance-1", nodeInfo.InstanceID)
+	assert.Equal(t, 3, requests)
+
+	// An unknown worker triggers a single refresh before failing.
+	_, err = updater.GetWorkerDetails(context.TODO(), "worker-4")
+	assert.NotNil(t, err)
+	assert.Equal(t, 6, requests)
+	assert.Equal(t, "generation=2", updater.StorageSecretConfig.RiaasEndpointURL.RawQuery)
+}
+
+func TestInstanceCacheConfigMap(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+	k8sClient := fake.NewSimpleClientset()
+
+	ic := NewInstanceCache(time.Minute)
+	assert.Nil(t, ic.LoadFromConfigMap(context.TODO(), k8sClient, "kube-system", "instance-cache"))
+	assert.True(t, ic.Expired())
+
+	ic.Fill(testInstances())
+	assert.Nil(t, ic.SaveToConfigMap(context.TODO(), k8sClient, "kube-system", "instance-cache", logger))
+	// Saving again updates the existing configmap.
+	ic.Fill(testInstances()[:2])
+	assert.Nil(t, ic.SaveToConfigMap(context.TODO(), k8sClient, "kube-system", "instance-cache", logger))
+
+	warm := NewInstanceCache(time.Minute)
+	assert.Nil(t, warm.LoadFromConfigMap(context.TODO(), k8sClient, "kube-system", "instance-cache"))
+	instance, ok := warm.Lookup("10.0.0.2")
+	assert.True(t, ok)
+	assert.Equal(t, "instance-2", instance.ID)
+	_, ok = warm.Lookup("worker-3")
+	assert.False(t, ok)
+
+	// Expired content is ignored.
+	cold := NewInstanceCache(-time.Second)
+	assert.Nil(t, cold.LoadFromConfigMap(context.TODO(), k8sClient, "kube-system", "instance-cache"))
+	assert.True(t, cold.Expired())
+}
@@ -0,0 +1,124 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+)
+
+// ConflictPolicy ...
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyOverwrite replaces the existing value with the one resolved from VPC.
+	ConflictPolicyOverwrite ConflictPolicy = "overwrite"
+	// ConflictPolicyKeep leaves the existing value on the node.
+	ConflictPolicyKeep ConflictPolicy = "keep"
+	// ConflictPolicyFail aborts the label update.
+	ConflictPolicyFail ConflictPolicy = "fail"
+
+	eventReasonLabelConflict = "LabelConflict"
+)
+
+// ParseConflictPolicy ...
+func ParseConflictPolicy(policy string) (ConflictPolicy, error) {
+	switch ConflictPolicy(policy) {
+	case ConflictPolicyOverwrite, ConflictPolicyKeep, ConflictPolicyFail:
+		return ConflictPolicy(policy), nil
+	}
+	return "", fmt.Errorf("invalid conflict policy %q, must be one of %q, %q, %q", policy, ConflictPolicyOverwrite, ConflictPolicyKeep, ConflictPolicyFail)
+}
+
+// LabelConflictPolicies decides what happens when a label the updater manages was already set to a
+// different value, e.g. by a cloud controller manager or kubelet --node-labels.
+type LabelConflictPolicies struct {
+	// Default applies to the label keys without their own policy. Empty means overwrite.
+	Default ConflictPolicy
+	// Keys holds the policy of individual label keys.
+	Keys map[string]ConflictPolicy
+}
+
+// ParseLabelConflictPolicies parses the default policy and a comma separated list of key=policy pairs.
+func ParseLabelConflictPolicies(defaultPolicy, keyPolicies string) (LabelConflictPolicies, error) {
+	var policies LabelConflictPolicies
+	var err error
+	if policies.Default, err = ParseConflictPolicy(defaultPolicy); err != nil {
+		return policies, err
+	}
+	policies.Keys = map[string]ConflictPolicy{}
+	for _, pair := range strings.Split(keyPolicies, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, policy, ok := strings.Cut(pair, "=")
+		if !ok {
+			return policies, fmt.Errorf("invalid label conflict policy %q, must be of the form key=policy", pair)
+		}
+		if policies.Keys[strings.TrimSpace(key)], err = ParseConflictPolicy(strings.TrimSpace(policy)); err != nil {
+			return policies, err
+		}
+	}
+	return policies, nil
+}
+
+// PolicyFor returns the conflict policy of a label key.
+func (p LabelConflictPolicies) PolicyFor(key string) ConflictPolicy {
+	if policy, ok := p.Keys[key]; ok {
+		return policy
+	}
+	if p.Default == "" {
+		return ConflictPolicyOverwrite
+	}
+	return p.Default
+}
+
+// resolveLabelConflict returns true if the desired value may be written to the label key of the node.
+// When the node already carries a different value set by someone else, the conflict is logged, recorded as a
+// warning event and counted, and the conflict policy of the key decides. A different value written by the
+// updater itself is no conflict.
+func (c *VpcNodeLabelUpdater) resolveLabelConflict(managed ManagedLabels, key, desired string) (bool, error) {
+	existing, ok := c.Node.ObjectMeta.Labels[key]
+	// The sentinel label is only ever written by the updater.
+	if !ok || existing == desired || key == vpcBlockLabelKey || key == labelSchemaVersionKey || managed.wrote(key, existing) {
+		return true, nil
+	}
+	return c.resolveConflict("Label", key, existing, desired)
+}
+
+// resolveConflict logs, records and counts the conflict of the existing value of a label or annotation of
+// the node, of the given kind, with the desired one, and returns true if the conflict policy of the key lets
+// the updater overwrite it.
+func (c *VpcNodeLabelUpdater) resolveConflict(kind, key, existing, desired string) (bool, error) {
+	policy := c.ConflictPolicies.PolicyFor(key)
+	labelConflictsTotal.WithLabelValues(key, string(policy)).Inc()
+	c.Logger.Warn(kind+" already set to a different value", zap.String("workerNodeName", c.Node.Name), zap.String(strings.ToLower(kind), key),
+		zap.String("existingValue", existing), zap.String("vpcValue", desired), zap.String("policy", string(policy)))
+	c.recordEvent(v1.EventTypeWarning, eventReasonLabelConflict, "%s %s is set to %q, VPC provider reports %q, conflict policy %s", kind, key, existing, desired, policy)
+
+	switch policy {
+	case ConflictPolicyKeep:
+		return false, nil
+	case ConflictPolicyFail:
+		return false, fmt.Errorf("%s %s of node %s is set to %q, VPC provider reports %q", strings.ToLower(kind), key, c.Node.Name, existing, desired)
+	}
+	return true, nil
+}
@@ -0,0 +1,116 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestParseLabelConflictPolicies(t *testing.T) {
+	policies, err := ParseLabelConflictPolicies("overwrite", "topology.kubernetes.io/zone=keep, topology.kubernetes.io/region=fail")
+	assert.Nil(t, err)
+	assert.Equal(t, ConflictPolicyKeep, policies.PolicyFor(topologyZoneLabelKey))
+	assert.Equal(t, ConflictPolicyFail, policies.PolicyFor(topologyRegionLabelKey))
+	assert.Equal(t, ConflictPolicyOverwrite, policies.PolicyFor(instanceIDLabelKey))
+	assert.Equal(t, ConflictPolicyOverwrite, LabelConflictPolicies{}.PolicyFor(instanceIDLabelKey))
+
+	_, err = ParseLabelConflictPolicies("ignore", "")
+	assert.NotNil(t, err)
+	_, err = ParseLabelConflictPolicies("keep", "topology.kubernetes.io/zone")
+	assert.NotNil(t, err)
+	_, err = ParseLabelConflictPolicies("keep", "topology.kubernetes.io/zone=ignore")
+	assert.NotNil(t, err)
+}
+
+func TestUpdateNodeLabelConflict(t *testing.T) {
+	testCases := []struct {
+		name    string
+		policy  ConflictPolicy
+		expZone string
+		expErr  bool
+	}{
+		{name: "overwrite", policy: ConflictPolicyOverwrite, expZone: "us-south-1"},
+		{name: "keep", policy: ConflictPolicyKeep, expZone: "ccm-zone"},
+		{name: "fail", policy: ConflictPolicyFail, expZone: "ccm-zone", expErr: true},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		updater := initCachedNodeLabelUpdater(t, testNode("worker-1", map[string]string{topologyZoneLabelKey: "ccm-zone"}))
+		updater.ConflictPolicies = LabelConflictPolicies{Keys: map[string]ConflictPolicy{topologyZoneLabelKey: tc.policy}}
+		conflicts := testutil.ToFloat64(labelConflictsTotal.WithLabelValues(topologyZoneLabelKey, string(tc.policy)))
+
+		_, err := updater.UpdateNodeLabel(context.TODO(), "worker-1")
+		assert.Equal(t, tc.expErr, err != nil)
+		assert.Equal(t, conflicts+1, testutil.ToFloat64(labelConflictsTotal.WithLabelValues(topologyZoneLabelKey, string(tc.policy))))
+		assert.Contains(t, <-updater.Recorder.(*record.FakeRecorder).Events, "Warning "+eventReasonLabelConflict)
+
+		node, err := updater.K8sClient.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expZone, node.Labels[topologyZoneLabelKey])
+	}
+}
+
+func TestVerifyNodeLabelConflict(t *testing.T) {
+	testCases := []struct {
+		name        string
+		written     string
+		expConflict bool
+	}{
+		{name: "own drift", written: "us-south-2"},
+		{name: "value of an older release", written: ""},
+		{name: "set by someone else", written: "us-south-3", expConflict: true},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		labels, _ := LabelSchemaOptions{}.desiredLabels(&NodeInfo{InstanceID: "instance-1", Region: "us-south", Zone: "us-south-1"})
+		labels[topologyZoneLabelKey] = "us-south-2"
+		node := testNode("worker-1", labels)
+		managed := ManagedLabels{}
+		for key, value := range labels {
+			managed[key] = ManagedLabel{Value: value}
+		}
+		managed[topologyZoneLabelKey] = ManagedLabel{Value: tc.written}
+		assert.Nil(t, managed.setOn(node))
+		updater := initCachedNodeLabelUpdater(t, node)
+		updater.DriftPolicy = DriftPolicyCorrect
+		conflicts := testutil.ToFloat64(labelConflictsTotal.WithLabelValues(topologyZoneLabelKey, string(ConflictPolicyOverwrite)))
+
+		drifts, err := updater.VerifyNodeLabel(context.TODO(), "worker-1")
+		assert.Nil(t, err)
+		assert.Len(t, drifts, 1)
+		events := strings.Join(drainEvents(updater.Recorder.(*record.FakeRecorder)), "\n")
+		assert.Contains(t, events, "Normal "+eventReasonLabelDriftCorrected)
+		if tc.expConflict {
+			assert.Equal(t, conflicts+1, testutil.ToFloat64(labelConflictsTotal.WithLabelValues(topologyZoneLabelKey, string(ConflictPolicyOverwrite))))
+			assert.Contains(t, events, "Warning "+eventReasonLabelConflict)
+		} else {
+			assert.Equal(t, conflicts, testutil.ToFloat64(labelConflictsTotal.WithLabelValues(topologyZoneLabelKey, string(ConflictPolicyOverwrite))))
+			assert.NotContains(t, events, eventReasonLabelConflict)
+		}
+		node, _ = updater.K8sClient.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+		written, _ := GetManagedLabels(node)
+		assert.Equal(t, "us-south-1", written[topologyZoneLabelKey].Value)
+	}
+}
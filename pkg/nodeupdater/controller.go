@@ -0,0 +1,236 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// ShardFilter restricts the nodes reconciled by a controller replica.
+type ShardFilter interface {
+	Owns(nodeName string) bool
+}
+
+// Controller watches the nodes of the cluster and reconciles the labels of every node added or
+// changed, and of all nodes every resync period.
+type Controller struct {
+	// updater holds the configuration shared by the reconciliation of all nodes.
+	updater  VpcNodeLabelUpdater
+	shard    ShardFilter
+	informer cache.SharedIndexInformer
+	queue    workqueue.TypedRateLimitingInterface[string]
+
+	startInformer sync.Once
+	// working is set while the workers run, lastProgress is the time in unix nanoseconds a worker
+	// last picked or finished a node and inFlight the number of nodes being reconciled.
+	working      atomic.Bool
+	lastProgress atomic.Int64
+	inFlight     atomic.Int32
+}
+
+// NewController returns a controller reconciling nodes with copies of the updater, whose Node is ignored.
+// The shard filter is optional, without it all nodes are reconciled.
+func NewController(updater *VpcNodeLabelUpdater, resyncPeriod time.Duration, shard ShardFilter) *Controller {
+	c := &Controller{
+		updater: *updater,
+		shard:   shard,
+		queue:   workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[string]()),
+	}
+	// Like the generated informers, let the reflector know whether the client supports watch lists,
+	// which the fake clientset does not.
+	c.informer = cache.NewSharedIndexInformer(cache.ToListWatcherWithWatchListSemantics(&cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return updater.K8sClient.CoreV1().Nodes().List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			return updater.K8sClient.CoreV1().Nodes().Watch(ctx, options)
+		},
+	}, updater.K8sClient), &v1.Node{}, resyncPeriod, cache.Indexers{})
+	_, _ = c.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{ // #nosec G104: the registration is never removed.
+		AddFunc: c.enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNode, newNode := oldObj.(*v1.Node), newObj.(*v1.Node)
+			// Nodes are updated for every status heartbeat, only label changes and resyncs are of interest.
+			if oldNode.ResourceVersion == newNode.ResourceVersion || !reflect.DeepEqual(oldNode.Labels, newNode.Labels) {
+				c.enqueue(newObj)
+			}
+		},
+	})
+	return c
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+// EnqueueAll queues all known nodes, e.g. after the nodes owned by this replica changed.
+func (c *Controller) EnqueueAll() {
+	for _, key := range c.informer.GetStore().ListKeys() {
+		c.queue.Add(key)
+	}
+}
+
+// Updater returns a copy of the updater reconciling the nodes.
+func (c *Controller) Updater() *VpcNodeLabelUpdater {
+	updater := c.updater
+	return &updater
+}
+
+// Nodes returns the known nodes owned by this replica.
+func (c *Controller) Nodes() []*v1.Node {
+	var nodes []*v1.Node
+	for _, obj := range c.informer.GetStore().List() {
+		node := obj.(*v1.Node)
+		if c.shard == nil || c.shard.Owns(node.Name) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// AllNodes returns all the known nodes, including those owned by other replicas.
+func (c *Controller) AllNodes() []*v1.Node {
+	var nodes []*v1.Node
+	for _, obj := range c.informer.GetStore().List() {
+		nodes = append(nodes, obj.(*v1.Node))
+	}
+	return nodes
+}
+
+// HasSynced returns true once the node informer has listed all the nodes.
+func (c *Controller) HasSynced() bool {
+	return c.informer.HasSynced()
+}
+
+// Start starts the node informer, if not started yet, so that its cache is warm before the workers run.
+// Replicas waiting for leadership start it to take over quickly.
+func (c *Controller) Start(ctx context.Context) {
+	c.startInformer.Do(func() {
+		go c.informer.RunWithContext(ctx)
+	})
+}
+
+// Run starts the node informer and the workers, and blocks until the context is done.
+func (c *Controller) Run(ctx context.Context, workers int) error {
+	defer c.queue.ShutDown()
+	logger := c.updater.Logger
+
+	c.Start(ctx)
+	logger.Info("Waiting for node informer to sync")
+	if !cache.WaitForCacheSync(ctx.Done(), c.informer.HasSynced) {
+		return fmt.Errorf("failed to sync node informer")
+	}
+	logger.Info("Starting node label workers", zap.Int("workers", workers))
+	c.lastProgress.Store(time.Now().UnixNano())
+	c.working.Store(true)
+	defer c.working.Store(false)
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}
+	<-ctx.Done()
+	logger.Info("Stopping node label workers")
+	return nil
+}
+
+// SyncedHealthCheck fails until the node informer has listed all the nodes.
+func (c *Controller) SyncedHealthCheck(r *http.Request) error {
+	if !c.informer.HasSynced() {
+		return fmt.Errorf("node informer not synced")
+	}
+	return nil
+}
+
+// StallHealthCheck returns a check failing when the workers made no progress for the timeout while
+// nodes were queued or being reconciled. The timeout must exceed the longest reconciliation, including
+// the retries of VPC requests.
+func (c *Controller) StallHealthCheck(timeout time.Duration) HealthCheck {
+	return func(r *http.Request) error {
+		if !c.working.Load() || (c.queue.Len() == 0 && c.inFlight.Load() == 0) {
+			return nil
+		}
+		if since := time.Since(time.Unix(0, c.lastProgress.Load())); since > timeout {
+			return fmt.Errorf("workqueue stalled, no node reconciled for %s with %d nodes queued and %d in flight",
+				since.Round(time.Second), c.queue.Len(), c.inFlight.Load())
+		}
+		return nil
+	}
+}
+
+func (c *Controller) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+// processNextItem reconciles the next queued node, and returns false once the queue is shut down.
+func (c *Controller) processNextItem(ctx context.Context) bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	c.inFlight.Add(1)
+	c.lastProgress.Store(time.Now().UnixNano())
+	defer func() {
+		c.inFlight.Add(-1)
+		c.lastProgress.Store(time.Now().UnixNano())
+		c.queue.Done(key)
+	}()
+
+	if err := c.reconcile(ctx, key); err != nil {
+		c.updater.Logger.Warn("Failed to reconcile node labels, requeuing", zap.String("workerNodeName", key),
+			zap.Int("requeues", c.queue.NumRequeues(key)), zap.Error(err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+// reconcile reconciles the labels of a node owned by this replica.
+func (c *Controller) reconcile(ctx context.Context, key string) error {
+	obj, exists, err := c.informer.GetStore().GetByKey(key)
+	if err != nil || !exists {
+		return err
+	}
+	if c.shard != nil && !c.shard.Owns(key) {
+		return nil
+	}
+	updater := c.updater
+	updater.Node = obj.(*v1.Node).DeepCopy()
+	return updater.ReconcileNodeLabel(ctx, updater.Node.Name)
+}
@@ -0,0 +1,94 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type ownsNodes map[string]bool
+
+func (o ownsNodes) Owns(nodeName string) bool {
+	return o[nodeName]
+}
+
+func TestController(t *testing.T) {
+	updater := initCachedNodeLabelUpdater(t, testNode("worker-1", nil))
+	updater.Recorder = nil
+	// The test logger is not safe for the concurrent workers.
+	updater.Logger = zap.NewNop()
+	updater.K8sClient = fake.NewSimpleClientset(testNode("worker-1", nil), testNode("worker-2", nil))
+	controller := NewController(updater, 0, ownsNodes{"worker-1": true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = controller.Run(ctx, 1)
+	}()
+	assert.Eventually(t, func() bool {
+		node, err := updater.K8sClient.CoreV1().Nodes().Get(ctx, "worker-1", metav1.GetOptions{})
+		return err == nil && node.ObjectMeta.Labels[instanceIDLabelKey] == "instance-1"
+	}, 10*time.Second, 50*time.Millisecond)
+
+	// Nodes owned by other replicas are left alone.
+	assert.Nil(t, controller.reconcile(ctx, "worker-2"))
+	node, err := updater.K8sClient.CoreV1().Nodes().Get(ctx, "worker-2", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, node.ObjectMeta.Labels)
+	// Deleted nodes are ignored.
+	assert.Nil(t, controller.reconcile(ctx, "worker-3"))
+}
+
+func TestTokenSource(t *testing.T) {
+	fetches := 0
+	lifetime := uint64(3600)
+	source := NewTokenSource(func() (string, uint64, error) {
+		fetches++
+		if lifetime == 0 {
+			return "", 0, errors.New("token exchange failed")
+		}
+		return "token", lifetime, nil
+	})
+	for i := 0; i < 3; i++ {
+		token, err := source.Token(context.TODO())
+		assert.Nil(t, err)
+		assert.Equal(t, "token", token)
+	}
+	assert.Equal(t, 1, fetches)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), source.ExpiresAt(), time.Minute)
+
+	// Tokens about to expire are replaced.
+	source.expiresAt = time.Now().Add(time.Minute)
+	lifetime = 0
+	_, err := source.Token(context.TODO())
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, fetches)
+
+	config := &StorageSecretConfig{IAMAccessToken: "static"}
+	token, err := config.AccessToken(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "static", token)
+}
@@ -0,0 +1,259 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+)
+
+// Environment variables of the credentials, overriding the values of the credentials file.
+const (
+	APIKeyEnv          = "VPC_API_KEY"
+	RiaasEndpointEnv   = "VPC_RIAAS_ENDPOINT_URL"
+	IAMEndpointEnv     = "VPC_IAM_ENDPOINT_URL"
+	ResourceGroupIDEnv = "VPC_RESOURCE_GROUP_ID"
+	TrustedProfileEnv  = "IBMCLOUD_PROFILEID"
+	CRTokenFileEnv     = "IBMC_VAULT_TOKEN_PATH"
+)
+
+const (
+	defaultIAMEndpointURL = "https://iam.cloud.ibm.com"
+	iamTokenPath          = "/identity/token"
+	iamAPIKeyGrantType    = "urn:ibm:params:oauth:grant-type:apikey"
+	iamCRTokenGrantType   = "urn:ibm:params:oauth:grant-type:cr-token"
+	iamRequestTimeout     = 30 * time.Second
+)
+
+// DefaultCRTokenFile is the projected service account token mounted by deploy/dep.yaml.
+const DefaultCRTokenFile = "/var/run/secrets/tokens/vault-token"
+
+// Credentials are the VPC credentials of clusters without the storage-secret-store secret. The fields
+// follow the vpc section of slclient.toml. Either the API key or the trusted profile ID is set: with the
+// profile, the service account token of CRTokenFile is exchanged for the IAM token, no API key is needed.
+type Credentials struct {
+	APIKey           string `toml:"g2_api_key" json:"g2_api_key"`
+	RiaasEndpointURL string `toml:"g2_riaas_endpoint_url" json:"g2_riaas_endpoint_url"`
+	IAMEndpointURL   string `toml:"g2_token_exchange_endpoint_url" json:"g2_token_exchange_endpoint_url"`
+	ResourceGroupID  string `toml:"g2_resource_group_id" json:"g2_resource_group_id"`
+	TrustedProfileID string `toml:"g2_trusted_profile_id" json:"g2_trusted_profile_id"`
+	CRTokenFile      string `toml:"g2_cr_token_file" json:"g2_cr_token_file"`
+}
+
+// credentialsFile is the slclient.toml layout, in TOML or YAML.
+type credentialsFile struct {
+	VPC Credentials `toml:"vpc" json:"vpc"`
+}
+
+// LoadCredentials reads the credentials from file, if set, and the environment. Environment variables
+// override the values of the file. Files ending in .yaml or .yml are read as YAML, any other as TOML.
+func LoadCredentials(file string) (*Credentials, error) {
+	credentials := &Credentials{}
+	if file != "" {
+		var err error
+		if credentials, err = readCredentialsFile(file); err != nil {
+			return nil, err
+		}
+	}
+	for env, value := range map[string]*string{
+		APIKeyEnv:          &credentials.APIKey,
+		RiaasEndpointEnv:   &credentials.RiaasEndpointURL,
+		IAMEndpointEnv:     &credentials.IAMEndpointURL,
+		ResourceGroupIDEnv: &credentials.ResourceGroupID,
+		TrustedProfileEnv:  &credentials.TrustedProfileID,
+		CRTokenFileEnv:     &credentials.CRTokenFile,
+	} {
+		if v, ok := os.LookupEnv(env); ok {
+			*value = v
+		}
+	}
+	return credentials.complete()
+}
+
+// readCredentialsFile reads the vpc section of a credentials file, as YAML if its name ends in .yaml or .yml and
+// as TOML otherwise.
+func readCredentialsFile(file string) (*Credentials, error) {
+	var config credentialsFile
+	data, err := os.ReadFile(file) // #nosec G304: the file is given by the operator.
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %v", err)
+	}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &config)
+	default:
+		_, err = toml.Decode(string(data), &config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file %s: %v", file, err)
+	}
+	return &config.VPC, nil
+}
+
+// complete sets the defaults of the credentials and validates them.
+func (c *Credentials) complete() (*Credentials, error) {
+	if c.IAMEndpointURL == "" {
+		c.IAMEndpointURL = defaultIAMEndpointURL
+	}
+	if c.TrustedProfileID != "" && c.CRTokenFile == "" {
+		c.CRTokenFile = DefaultCRTokenFile
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if c.TrustedProfileID != "" {
+		if _, err := readCRToken(c.CRTokenFile); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Validate fails naming every missing or invalid field.
+func (c *Credentials) Validate() error {
+	var problems []string
+	switch {
+	case c.APIKey == "" && c.TrustedProfileID == "":
+		problems = append(problems, fmt.Sprintf("g2_api_key (%s) or g2_trusted_profile_id (%s) is missing", APIKeyEnv, TrustedProfileEnv))
+	case c.APIKey != "" && c.TrustedProfileID != "":
+		problems = append(problems, fmt.Sprintf("only one of g2_api_key (%s) and g2_trusted_profile_id (%s) can be set", APIKeyEnv, TrustedProfileEnv))
+	case c.TrustedProfileID != "" && c.CRTokenFile == "":
+		problems = append(problems, fmt.Sprintf("g2_cr_token_file (%s) is missing", CRTokenFileEnv))
+	}
+	for _, endpoint := range []struct{ name, env, value string }{
+		{"g2_riaas_endpoint_url", RiaasEndpointEnv, c.RiaasEndpointURL},
+		{"g2_token_exchange_endpoint_url", IAMEndpointEnv, c.IAMEndpointURL},
+	} {
+		if endpoint.value == "" {
+			problems = append(problems, fmt.Sprintf("%s (%s) is missing", endpoint.name, endpoint.env))
+			continue
+		}
+		if _, err := normalizeEndpointURL(endpoint.value, zap.NewNop()); err != nil {
+			problems = append(problems, fmt.Sprintf("%s (%s) is invalid: %v", endpoint.name, endpoint.env, err))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid credentials: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// CredentialsSource sets up the secret configuration from credentials loaded by LoadCredentials.
+func CredentialsSource(credentials *Credentials, ctxLogger *zap.Logger) SecretConfigSource {
+	return func() (*StorageSecretConfig, error) {
+		return newCredentialsSecretConfig(credentials, ctxLogger)
+	}
+}
+
+// newCredentialsSecretConfig sets up the RIAAS endpoint and the API key or trusted profile token source of
+// the credentials, without fetching a token. The token requests use the HTTP client of the configuration, which
+// WithHTTPClient sets after the source returns.
+func newCredentialsSecretConfig(credentials *Credentials, ctxLogger *zap.Logger) (*StorageSecretConfig, error) {
+	if err := credentials.Validate(); err != nil {
+		return nil, err
+	}
+	riaasURL, err := normalizeEndpointURL(credentials.RiaasEndpointURL, ctxLogger)
+	if err != nil {
+		return nil, err
+	}
+	iamURL, err := normalizeEndpointURL(credentials.IAMEndpointURL, ctxLogger)
+	if err != nil {
+		return nil, err
+	}
+	storageSecretConfig := &StorageSecretConfig{
+		RiaasEndpointURL: instancesURL(riaasURL),
+		ResourceGroupID:  credentials.ResourceGroupID,
+	}
+	fetch := func() (string, uint64, error) {
+		return requestIAMToken(storageSecretConfig.httpClient(), iamURL.String(), url.Values{"grant_type": {iamAPIKeyGrantType}, "apikey": {credentials.APIKey}})
+	}
+	if credentials.TrustedProfileID != "" {
+		ctxLogger.Info("Authenticating with trusted profile", zap.String("profileID", credentials.TrustedProfileID),
+			zap.String("tokenFile", credentials.CRTokenFile))
+		fetch = func() (string, uint64, error) {
+			// The projected token is rotated by the kubelet, it is read again for every exchange.
+			crToken, err := readCRToken(credentials.CRTokenFile)
+			if err != nil {
+				return "", 0, err
+			}
+			return requestIAMToken(storageSecretConfig.httpClient(), iamURL.String(), url.Values{"grant_type": {iamCRTokenGrantType}, "cr_token": {crToken},
+				"profile_id": {credentials.TrustedProfileID}})
+		}
+	}
+	storageSecretConfig.TokenSource = NewTokenSource(fetch)
+	return storageSecretConfig, nil
+}
+
+// readCRToken reads the service account token exchanged for the IAM token of the trusted profile.
+func readCRToken(file string) (string, error) {
+	data, err := os.ReadFile(file) // #nosec G304: the file is given by the operator.
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account token of the trusted profile: %v", err)
+	}
+	crToken := strings.TrimSpace(string(data))
+	if crToken == "" {
+		return "", fmt.Errorf("service account token file %s of the trusted profile is empty", file)
+	}
+	return crToken, nil
+}
+
+// requestIAMToken exchanges the grant of form for an IAM token at the IAM endpoint with client and returns the
+// token and its lifetime in seconds.
+func requestIAMToken(client *http.Client, iamURL string, form url.Values) (string, uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), iamRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, iamURL+iamTokenPath, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("IAM token request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read IAM token response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("IAM token request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var tokenResponse AccessTokenResponse
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return "", 0, errors.New("failed to unmarshal json response of IAM token")
+	}
+	if tokenResponse.AccessToken == "" || tokenResponse.ExpiresIn <= 0 {
+		return "", 0, errors.New("IAM token response holds no token")
+	}
+	return tokenResponse.AccessToken, uint64(tokenResponse.ExpiresIn), nil
+}
@@ -0,0 +1,167 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadCredentials(t *testing.T) {
+	yamlFile := filepath.Join(t.TempDir(), "credentials.yaml")
+	assert.Nil(t, os.WriteFile(yamlFile, []byte("vpc:\n  g2_api_key: yaml-key\n  g2_riaas_endpoint_url: https://eu-de.iaas.cloud.ibm.com\n"), 0600))
+	tokenFile := filepath.Join(t.TempDir(), "vault-token")
+	assert.Nil(t, os.WriteFile(tokenFile, []byte("sa-token\n"), 0600))
+
+	testCases := []struct {
+		name           string
+		file           string
+		env            map[string]string
+		expCredentials *Credentials
+		expErr         string
+	}{
+		{
+			name: "TOML file",
+			file: filepath.Join("..", "..", "test-fixtures", "slclient.toml"),
+			expCredentials: &Credentials{APIKey: "api-key", RiaasEndpointURL: "https://us-south.iaas.cloud.ibm.com",
+				IAMEndpointURL: "https://iam.cloud.ibm.com", ResourceGroupID: "resource-group-id"},
+		},
+		{
+			name:           "YAML file with the default IAM endpoint",
+			file:           yamlFile,
+			expCredentials: &Credentials{APIKey: "yaml-key", RiaasEndpointURL: "https://eu-de.iaas.cloud.ibm.com", IAMEndpointURL: defaultIAMEndpointURL},
+		},
+		{
+			name: "Environment overriding the file",
+			file: filepath.Join("..", "..", "test-fixtures", "invalid-slclient.toml"),
+			env:  map[string]string{RiaasEndpointEnv: "https://eu-gb.iaas.cloud.ibm.com", IAMEndpointEnv: "https://private.iam.cloud.ibm.com"},
+			expCredentials: &Credentials{APIKey: "api-key", RiaasEndpointURL: "https://eu-gb.iaas.cloud.ibm.com",
+				IAMEndpointURL: "https://private.iam.cloud.ibm.com", ResourceGroupID: "resource-group-id"},
+		},
+		{
+			name:   "Missing RIAAS endpoint",
+			file:   filepath.Join("..", "..", "test-fixtures", "invalid-slclient.toml"),
+			expErr: "invalid credentials: g2_riaas_endpoint_url (VPC_RIAAS_ENDPOINT_URL) is missing",
+		},
+		{
+			name:   "Environment only",
+			env:    map[string]string{RiaasEndpointEnv: "ftp://us-south.iaas.cloud.ibm.com"},
+			expErr: "invalid credentials: g2_api_key (VPC_API_KEY) or g2_trusted_profile_id (IBMCLOUD_PROFILEID) is missing, g2_riaas_endpoint_url (VPC_RIAAS_ENDPOINT_URL) is invalid: invalid scheme \"ftp\"",
+		},
+		{
+			name: "Trusted profile",
+			env:  map[string]string{TrustedProfileEnv: "Profile-1", CRTokenFileEnv: tokenFile, RiaasEndpointEnv: "https://us-south.iaas.cloud.ibm.com"},
+			expCredentials: &Credentials{TrustedProfileID: "Profile-1", CRTokenFile: tokenFile, RiaasEndpointURL: "https://us-south.iaas.cloud.ibm.com",
+				IAMEndpointURL: defaultIAMEndpointURL},
+		},
+		{
+			name:   "Trusted profile and API key",
+			file:   filepath.Join("..", "..", "test-fixtures", "slclient.toml"),
+			env:    map[string]string{TrustedProfileEnv: "Profile-1", CRTokenFileEnv: tokenFile},
+			expErr: "only one of g2_api_key (VPC_API_KEY) and g2_trusted_profile_id (IBMCLOUD_PROFILEID) can be set",
+		},
+		{
+			name:   "Trusted profile without token file",
+			env:    map[string]string{TrustedProfileEnv: "Profile-1", CRTokenFileEnv: filepath.Join(t.TempDir(), "missing"), RiaasEndpointEnv: "https://us-south.iaas.cloud.ibm.com"},
+			expErr: "failed to read service account token of the trusted profile",
+		},
+		{
+			name:   "Missing file",
+			file:   filepath.Join("..", "..", "test-fixtures", "missing.toml"),
+			expErr: "failed to read credentials file",
+		},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		for _, env := range []string{APIKeyEnv, RiaasEndpointEnv, IAMEndpointEnv, ResourceGroupIDEnv, TrustedProfileEnv, CRTokenFileEnv} {
+			value, ok := tc.env[env]
+			if !ok {
+				// t.Setenv restores the variable at the end of the test, before unsetting it.
+				t.Setenv(env, "")
+				os.Unsetenv(env)
+				continue
+			}
+			t.Setenv(env, value)
+		}
+		credentials, err := LoadCredentials(tc.file)
+		if tc.expErr != "" {
+			assert.ErrorContains(t, err, tc.expErr)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expCredentials, credentials)
+	}
+}
+
+func TestCredentialsSource(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, iamTokenPath, r.URL.Path)
+		assert.Nil(t, r.ParseForm())
+		if r.PostForm.Get("grant_type") == iamCRTokenGrantType && r.PostForm.Get("profile_id") == "Profile-1" {
+			_, _ = w.Write([]byte(`{"access_token":"profile-token-for-` + r.PostForm.Get("cr_token") + `","expires_in":3600}`))
+			return
+		}
+		if r.PostForm.Get("apikey") != "api-key" || r.PostForm.Get("grant_type") != iamAPIKeyGrantType {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errorMessage":"invalid api key"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"iam-token","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	credentials := &Credentials{APIKey: "api-key", RiaasEndpointURL: "https://us-south.iaas.cloud.ibm.com/", IAMEndpointURL: server.URL,
+		ResourceGroupID: "resource-group-id"}
+	config, err := ReadSecretConfigurationFrom(context.TODO(), WithHTTPClient(CredentialsSource(credentials, logger), server.Client()), logger)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "iam-token", config.IAMAccessToken)
+	assert.Equal(t, "resource-group-id", config.ResourceGroupID)
+	assert.Equal(t, "/v1/instances", config.RiaasEndpointURL.Path)
+	assert.Equal(t, "us-south.iaas.cloud.ibm.com", config.RiaasEndpointURL.Host)
+
+	credentials.APIKey = "wrong"
+	_, err = ReadSecretConfigurationFrom(context.TODO(), WithHTTPClient(CredentialsSource(credentials, logger), server.Client()), logger)
+	assert.ErrorContains(t, err, "status 400")
+
+	_, err = CredentialsSource(&Credentials{}, logger)()
+	assert.NotNil(t, err)
+
+	// The rotated service account token is read for the next exchange.
+	tokenFile := filepath.Join(t.TempDir(), "vault-token")
+	assert.Nil(t, os.WriteFile(tokenFile, []byte("sa-token-1"), 0600))
+	credentials = &Credentials{TrustedProfileID: "Profile-1", CRTokenFile: tokenFile, RiaasEndpointURL: "https://us-south.iaas.cloud.ibm.com",
+		IAMEndpointURL: server.URL}
+	config, err = ReadSecretConfigurationFrom(context.TODO(), WithHTTPClient(CredentialsSource(credentials, logger), server.Client()), logger)
+	assert.Nil(t, err)
+	assert.Equal(t, "profile-token-for-sa-token-1", config.IAMAccessToken)
+	assert.Nil(t, os.WriteFile(tokenFile, []byte("sa-token-2"), 0600))
+	token, _, err := config.TokenSource.fetch()
+	assert.Nil(t, err)
+	assert.Equal(t, "profile-token-for-sa-token-2", token)
+}
@@ -18,6 +18,7 @@
 package nodeupdater
 
 import (
+	"net/http"
 	"net/url"
 	"time"
 )
@@ -27,12 +28,21 @@ type NodeInfo struct {
 	InstanceID string
 	Region     string
 	Zone       string
+	// Instance is the VPC instance of the node, available to node label policy templates.
+	Instance *Instance `json:"-"`
 }
 
 // StorageSecretConfig ...
 type StorageSecretConfig struct {
 	RiaasEndpointURL *url.URL
 	IAMAccessToken   string
+	// TokenSource is optional. When set, it replaces IAMAccessToken and keeps the token fresh.
+	TokenSource *TokenSource
+	// ResourceGroupID is optional, the resource group of the credentials. It only filters the instance list
+	// once merged into the instance list filter.
+	ResourceGroupID string
+	// HTTPClient is optional, the client of the RIAAS and IAM requests. nil is http.DefaultClient.
+	HTTPClient *http.Client
 }
 
 // AccessTokenResponse ...
@@ -89,15 +89,15 @@ func initMockNodeLabelUpdater(t *testing.T) *MockVPCNodeLabelUpdater {
 }
 
 // GetWorkerDetails ...
-func (m *MockVPCNodeLabelUpdater) GetWorkerDetails(workerNodeName string) (*NodeInfo, error) {
+func (m *MockVPCNodeLabelUpdater) GetWorkerDetails(ctx context.Context, workerNodeName string) (*NodeInfo, error) {
 	if strings.Contains(workerNodeName, "ip") {
-		return m.GetInstanceByIP(workerNodeName)
+		return m.GetInstanceByIP(ctx, workerNodeName)
 	}
-	return m.GetInstanceByName(workerNodeName)
+	return m.GetInstanceByName(ctx, workerNodeName)
 }
 
 // GetInstancesFromVPC ...
-func (m *MockVPCNodeLabelUpdater) GetInstancesFromVPC(riaasInstanceURL *url.URL) ([]*Instance, error) {
+func (m *MockVPCNodeLabelUpdater) GetInstancesFromVPC(ctx context.Context, riaasInstanceURL *url.URL) ([]*Instance, error) {
 	fmt.Println(m.StorageSecretConfig.IAMAccessToken)
 	if m.StorageSecretConfig.IAMAccessToken == "" {
 		return nil, errors.New("failed to get worker details as instance list is empty")
@@ -120,11 +120,11 @@ func (m *MockVPCNodeLabelUpdater) GetInstancesFromVPC(riaasInstanceURL *url.URL)
 }
 
 // GetInstanceByName ...
-func (m *MockVPCNodeLabelUpdater) GetInstanceByName(workerNodeName string) (*NodeInfo, error) {
+func (m *MockVPCNodeLabelUpdater) GetInstanceByName(ctx context.Context, workerNodeName string) (*NodeInfo, error) {
 	if strings.Contains(workerNodeName, "invalid") {
 		return nil, errors.New("failed to get worker details as instance list is empty")
 	}
-	insL, err := m.GetInstancesFromVPC(m.StorageSecretConfig.RiaasEndpointURL)
+	insL, err := m.GetInstancesFromVPC(ctx, m.StorageSecretConfig.RiaasEndpointURL)
 	if err != nil {
 		return nil, err
 	}
@@ -132,7 +132,7 @@ func (m *MockVPCNodeLabelUpdater) GetInstanceByName(workerNodeName string) (*Nod
 }
 
 // GetInstanceByIP ...
-func (m *MockVPCNodeLabelUpdater) GetInstanceByIP(workerNodeName string) (*NodeInfo, error) {
+func (m *MockVPCNodeLabelUpdater) GetInstanceByIP(ctx context.Context, workerNodeName string) (*NodeInfo, error) {
 	if strings.Contains(workerNodeName, "invalid-ip") {
 		return nil, errors.New("failed to get worker details as instance list is empty")
 	}
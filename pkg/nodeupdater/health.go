@@ -0,0 +1,122 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// riaasCheckTimeout bounds a single reachability check of the RIAAS endpoint.
+	riaasCheckTimeout = 5 * time.Second
+)
+
+// HealthCheck returns an error when the checked component is unhealthy.
+type HealthCheck func(r *http.Request) error
+
+// HealthHandler serves the result of the health checks: 200 when all pass, 500 listing the failed
+// checks otherwise. The ?verbose query parameter lists the passed checks as well.
+func HealthHandler(checks map[string]HealthCheck) http.Handler {
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var output strings.Builder
+		failed := false
+		for _, name := range names {
+			if err := checks[name](r); err != nil {
+				failed = true
+				fmt.Fprintf(&output, "[-]%s failed: %v\n", name, err)
+				continue
+			}
+			fmt.Fprintf(&output, "[+]%s ok\n", name)
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if failed {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprint(w, output.String()) // #nosec G104: nothing to do if the probe went away.
+			return
+		}
+		if _, ok := r.URL.Query()["verbose"]; ok {
+			_, _ = fmt.Fprint(w, output.String()) // #nosec G104: nothing to do if the probe went away.
+			return
+		}
+		_, _ = fmt.Fprint(w, "ok") // #nosec G104: nothing to do if the probe went away.
+	})
+}
+
+// CachedHealthCheck runs the check at most once per interval and returns the last result in between,
+// for checks calling remote services which probes must not hammer.
+func CachedHealthCheck(check HealthCheck, interval time.Duration) HealthCheck {
+	var mutex sync.Mutex
+	var checkedAt time.Time
+	var lastErr error
+	return func(r *http.Request) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if !checkedAt.IsZero() && time.Since(checkedAt) < interval {
+			return lastErr
+		}
+		lastErr = check(r)
+		checkedAt = time.Now()
+		return lastErr
+	}
+}
+
+// TokenHealthCheck fails when no valid IAM token can be obtained.
+func (s *StorageSecretConfig) TokenHealthCheck(r *http.Request) error {
+	if err := s.checkToken(r.Context()); err != nil {
+		return err
+	}
+	if s.TokenSource != nil && time.Now().After(s.TokenSource.ExpiresAt()) {
+		return fmt.Errorf("IAM token expired at %s", s.TokenSource.ExpiresAt().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// RIAASHealthCheck fails when the RIAAS endpoint read by ReadSecretConfiguration can't be reached.
+// Any HTTP response proves reachability, the request is not authenticated.
+func (s *StorageSecretConfig) RIAASHealthCheck(r *http.Request) error {
+	return s.checkRIAASReachable(r.Context())
+}
+
+// checkRIAASReachable sends an unauthenticated HEAD request to the RIAAS endpoint.
+func (s *StorageSecretConfig) checkRIAASReachable(ctx context.Context) error {
+	endpoint := &url.URL{Scheme: s.RiaasEndpointURL.Scheme, Host: s.RiaasEndpointURL.Host, Path: "/"}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, endpoint.String(), nil)
+	if err != nil {
+		return err
+	}
+	client := *s.httpClient()
+	client.Timeout = riaasCheckTimeout
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("RIAAS endpoint %s not reachable: %v", endpoint.Host, err)
+	}
+	return resp.Body.Close()
+}
@@ -0,0 +1,108 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestHealthHandler(t *testing.T) {
+	pass := func(r *http.Request) error { return nil }
+	fail := func(r *http.Request) error { return errors.New("broken") }
+	testCases := []struct {
+		name    string
+		checks  map[string]HealthCheck
+		query   string
+		expCode int
+		expBody string
+	}{
+		{name: "all pass", checks: map[string]HealthCheck{"a": pass, "b": pass}, expCode: http.StatusOK, expBody: "ok"},
+		{name: "verbose", checks: map[string]HealthCheck{"a": pass, "b": pass}, query: "?verbose", expCode: http.StatusOK, expBody: "[+]a ok\n[+]b ok\n"},
+		{name: "one fails", checks: map[string]HealthCheck{"a": pass, "b": fail}, expCode: http.StatusInternalServerError, expBody: "[+]a ok\n[-]b failed: broken\n"},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		recorder := httptest.NewRecorder()
+		HealthHandler(tc.checks).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz"+tc.query, nil))
+		assert.Equal(t, tc.expCode, recorder.Code)
+		assert.Equal(t, tc.expBody, recorder.Body.String())
+	}
+}
+
+func TestCachedHealthCheck(t *testing.T) {
+	calls := 0
+	check := CachedHealthCheck(func(r *http.Request) error {
+		calls++
+		return nil
+	}, time.Hour)
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, check(nil))
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestSecretConfigHealthChecks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL + "/v1/instances?generation=2")
+	request := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+	config := &StorageSecretConfig{RiaasEndpointURL: serverURL, IAMAccessToken: "token"}
+	assert.Nil(t, config.TokenHealthCheck(request))
+	assert.Nil(t, config.RIAASHealthCheck(request))
+
+	server.Close()
+	assert.NotNil(t, config.RIAASHealthCheck(request))
+
+	config.TokenSource = NewTokenSource(func() (string, uint64, error) { return "", 0, errors.New("token exchange failed") })
+	assert.NotNil(t, config.TokenHealthCheck(request))
+	config.TokenSource = NewTokenSource(func() (string, uint64, error) { return "token", 3600, nil })
+	assert.Nil(t, config.TokenHealthCheck(request))
+}
+
+func TestStallHealthCheck(t *testing.T) {
+	updater := initCachedNodeLabelUpdater(t, testNode("worker-1", nil))
+	updater.K8sClient = fake.NewSimpleClientset()
+	controller := NewController(updater, 0, nil)
+	check := controller.StallHealthCheck(time.Minute)
+	assert.NotNil(t, controller.SyncedHealthCheck(nil))
+
+	// Queued nodes are not a stall before the workers run, e.g. while waiting for leadership.
+	controller.queue.Add("worker-1")
+	assert.Nil(t, check(nil))
+
+	controller.working.Store(true)
+	controller.lastProgress.Store(time.Now().UnixNano())
+	assert.Nil(t, check(nil))
+	controller.lastProgress.Store(time.Now().Add(-time.Hour).UnixNano())
+	assert.NotNil(t, check(nil))
+
+	controller.queue.Get()
+	controller.inFlight.Add(1)
+	assert.NotNil(t, check(nil), "a node stuck in flight is a stall")
+}
@@ -0,0 +1,95 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// instanceFilterDiscoveryNodes bounds the labeled nodes listed to discover the instance list filter.
+const instanceFilterDiscoveryNodes = 10
+
+// InstanceListFilter narrows the instance list queries with the server-side filters of the VPC API, so that
+// only the instances of the cluster are downloaded instead of all the instances of the account. Empty fields
+// don't filter. The API has no filter on the primary IP, nodes named by IP are matched in the filtered list.
+type InstanceListFilter struct {
+	VPCID           string
+	ResourceGroupID string
+}
+
+// apply sets the query parameters of the filter.
+func (f InstanceListFilter) apply(q url.Values) {
+	if f.VPCID != "" {
+		q.Set("vpc.id", f.VPCID)
+	}
+	if f.ResourceGroupID != "" {
+		q.Set("resource_group.id", f.ResourceGroupID)
+	}
+}
+
+// Merge returns the filter with its empty fields set from other.
+func (f InstanceListFilter) Merge(other InstanceListFilter) InstanceListFilter {
+	if f.VPCID == "" {
+		f.VPCID = other.VPCID
+	}
+	if f.ResourceGroupID == "" {
+		f.ResourceGroupID = other.ResourceGroupID
+	}
+	return f
+}
+
+// DiscoverInstanceListFilter returns the VPC and resource group of the instance of a node already labeled by the
+// updater, read with a single instance request. It fails when no labeled node is known, e.g. for the first node
+// of the cluster. The filter only fits clusters whose instances share the VPC and resource group.
+func (c *VpcNodeLabelUpdater) DiscoverInstanceListFilter(ctx context.Context) (_ InstanceListFilter, err error) {
+	ctx, span := StartSpan(ctx, "DiscoverInstanceListFilter")
+	defer func() { EndSpan(span, err) }()
+
+	nodes, err := c.K8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: instanceIDLabelKey, Limit: instanceFilterDiscoveryNodes})
+	if err != nil {
+		return InstanceListFilter{}, fmt.Errorf("failed to list labeled nodes: %v", err)
+	}
+	for _, node := range nodes.Items {
+		instanceID := node.Labels[instanceIDLabelKey]
+		if instanceID == "" {
+			continue
+		}
+		instance, err := c.getInstance(ctx, instanceID)
+		if err != nil {
+			c.Logger.Warn("Failed to get instance of labeled node", zap.String("node", node.Name), zap.String("instanceID", instanceID), zap.Error(err))
+			continue
+		}
+		filter := InstanceListFilter{}
+		if instance.Vpc != nil {
+			filter.VPCID = instance.Vpc.ID
+		}
+		if instance.ResourceGroup != nil {
+			filter.ResourceGroupID = instance.ResourceGroup.ID
+		}
+		c.Logger.Info("Discovered instance list filter", zap.String("node", node.Name), zap.String("vpcID", filter.VPCID),
+			zap.String("resourceGroupID", filter.ResourceGroupID))
+		return filter, nil
+	}
+	return InstanceListFilter{}, errors.New("no labeled node with a known instance")
+}
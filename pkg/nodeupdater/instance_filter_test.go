@@ -0,0 +1,108 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestInstanceListURLFilter(t *testing.T) {
+	riaasURL, _ := url.Parse("https://us-south.iaas.cloud.ibm.com/v1/instances?generation=2&version=2020-01-01")
+	testCases := []struct {
+		name     string
+		filter   InstanceListFilter
+		params   url.Values
+		expQuery url.Values
+	}{
+		{name: "No filter", params: url.Values{"limit": {"100"}},
+			expQuery: url.Values{"generation": {"2"}, "version": {"2020-01-01"}, "limit": {"100"}}},
+		{name: "VPC filter", filter: InstanceListFilter{VPCID: "vpc-1"}, params: url.Values{"limit": {"100"}},
+			expQuery: url.Values{"generation": {"2"}, "version": {"2020-01-01"}, "limit": {"100"}, "vpc.id": {"vpc-1"}}},
+		{name: "VPC and resource group filter", filter: InstanceListFilter{VPCID: "vpc-1", ResourceGroupID: "rg-1"}, params: url.Values{"name": {"node-1"}},
+			expQuery: url.Values{"generation": {"2"}, "version": {"2020-01-01"}, "name": {"node-1"}, "vpc.id": {"vpc-1"}, "resource_group.id": {"rg-1"}}},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		updater := &VpcNodeLabelUpdater{StorageSecretConfig: &StorageSecretConfig{RiaasEndpointURL: riaasURL}, InstanceListFilter: tc.filter}
+		assert.Equal(t, tc.expQuery, updater.instanceListURL(tc.params).Query())
+	}
+
+	// The resource group of the credentials only filters when set in the filter.
+	updater := &VpcNodeLabelUpdater{StorageSecretConfig: &StorageSecretConfig{RiaasEndpointURL: riaasURL, ResourceGroupID: "rg-credentials"}}
+	assert.False(t, updater.instanceListURL(nil).Query().Has("resource_group.id"))
+	assert.Equal(t, "generation=2&version=2020-01-01", riaasURL.RawQuery)
+}
+
+func TestInstanceListFilterMerge(t *testing.T) {
+	filter := InstanceListFilter{VPCID: "vpc-flag"}.Merge(InstanceListFilter{VPCID: "vpc-discovered", ResourceGroupID: "rg-discovered"})
+	assert.Equal(t, InstanceListFilter{VPCID: "vpc-flag", ResourceGroupID: "rg-discovered"}, filter)
+}
+
+func TestDiscoverInstanceListFilter(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/instances/instance-1":
+			_, _ = w.Write([]byte(`{"id":"instance-1","vpc":{"id":"vpc-1"},"resource_group":{"id":"rg-1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	riaasURL, _ := url.Parse(server.URL + "/v1/instances?generation=2&version=2020-01-01")
+
+	labeledNode := func(name, instanceID string) *v1.Node {
+		return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{instanceIDLabelKey: instanceID}}}
+	}
+	testCases := []struct {
+		name      string
+		nodes     []*v1.Node
+		expFilter InstanceListFilter
+		expErr    bool
+	}{
+		{name: "Labeled node", nodes: []*v1.Node{labeledNode("node-1", "instance-1")}, expFilter: InstanceListFilter{VPCID: "vpc-1", ResourceGroupID: "rg-1"}},
+		{name: "Deleted instance of a labeled node", nodes: []*v1.Node{labeledNode("node-2", "instance-2")}, expErr: true},
+		{name: "No labeled node", nodes: []*v1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "node-3"}}}, expErr: true},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		client := fake.NewSimpleClientset()
+		for _, node := range tc.nodes {
+			_, _ = client.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
+		}
+		updater := &VpcNodeLabelUpdater{
+			K8sClient:           client,
+			Logger:              logger,
+			StorageSecretConfig: &StorageSecretConfig{RiaasEndpointURL: riaasURL, IAMAccessToken: "token", HTTPClient: server.Client()},
+		}
+		filter, err := updater.DiscoverInstanceListFilter(context.TODO())
+		assert.Equal(t, tc.expErr, err != nil)
+		assert.Equal(t, tc.expFilter, filter)
+	}
+}
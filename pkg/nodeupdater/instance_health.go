@@ -0,0 +1,268 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// InstanceHealthyConditionType is the node condition reporting whether the VPC instance of the node exists and runs.
+	InstanceHealthyConditionType v1.NodeConditionType = "VPCInstanceHealthy"
+	// DefaultInstanceUnhealthyTaint is the default taint of nodes whose VPC instance is unhealthy.
+	DefaultInstanceUnhealthyTaint = "vpc.ibm.com/instance-unhealthy:NoSchedule"
+
+	instanceStatusStopped  = "stopped"
+	instanceStatusFailed   = "failed"
+	instanceStatusDeleting = "deleting"
+
+	instanceReasonRunning  = "InstanceRunning"
+	instanceReasonNotFound = "InstanceNotFound"
+	instanceReasonStopped  = "InstanceStopped"
+	instanceReasonFailed   = "InstanceFailed"
+
+	eventReasonInstanceUnhealthy = "VPCInstanceUnhealthy"
+	eventReasonInstanceRecovered = "VPCInstanceRecovered"
+)
+
+// ParseTaint parses a taint of the form key[=value]:effect, as accepted by kubectl taint.
+func ParseTaint(spec string) (*v1.Taint, error) {
+	keyValue, effect, found := strings.Cut(spec, ":")
+	if !found || keyValue == "" {
+		return nil, fmt.Errorf("invalid taint %q, must be of the form key[=value]:effect", spec)
+	}
+	switch v1.TaintEffect(effect) {
+	case v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute:
+	default:
+		return nil, fmt.Errorf("invalid taint effect %q, must be one of %q, %q, %q", effect, v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute)
+	}
+	key, value, _ := strings.Cut(keyValue, "=")
+	return &v1.Taint{Key: key, Value: value, Effect: v1.TaintEffect(effect)}, nil
+}
+
+// instanceHealth returns the status, reason and message of the VPC instance condition of a node.
+// Instances being started, stopped or restarted are considered healthy until they settle.
+func instanceHealth(instanceID string, instance *Instance) (v1.ConditionStatus, string, string) {
+	if instance == nil {
+		return v1.ConditionFalse, instanceReasonNotFound, fmt.Sprintf("VPC instance %s does not exist", instanceID)
+	}
+	switch instance.Status {
+	case instanceStatusDeleting:
+		return v1.ConditionFalse, instanceReasonNotFound, fmt.Sprintf("VPC instance %s is being deleted", instanceID)
+	case instanceStatusStopped:
+		return v1.ConditionFalse, instanceReasonStopped, fmt.Sprintf("VPC instance %s is stopped", instanceID)
+	case instanceStatusFailed:
+		return v1.ConditionFalse, instanceReasonFailed, fmt.Sprintf("VPC instance %s has failed", instanceID)
+	}
+	return v1.ConditionTrue, instanceReasonRunning, fmt.Sprintf("VPC instance %s is %s", instanceID, instance.Status)
+}
+
+// InstanceHealthMonitor periodically checks the status of the VPC instance of every labeled node. It sets the
+// VPCInstanceHealthy condition of the nodes, and taints the nodes whose instance is missing, stopped or failed
+// until it recovers.
+type InstanceHealthMonitor struct {
+	Updater *VpcNodeLabelUpdater
+	// Nodes returns the nodes to check.
+	Nodes    func() []*v1.Node
+	Interval time.Duration
+	// Taint is optional, without it only the condition is set.
+	Taint *v1.Taint
+}
+
+// Run checks the instances every interval until the context is done.
+func (m *InstanceHealthMonitor) Run(ctx context.Context) {
+	m.Updater.Logger.Info("Starting VPC instance health monitor", zap.Duration("interval", m.Interval))
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := m.Check(ctx); err != nil {
+			m.Updater.Logger.Warn("Failed to check VPC instance health", zap.Error(err))
+		}
+	}, m.Interval)
+}
+
+// Check lists the VPC instances once and updates the condition and taint of the nodes whose instance health changed.
+// Instances missing from the listing, e.g. excluded by the instance list filter, are got by ID, and only
+// considered deleted if VPC reports them as not found.
+func (m *InstanceHealthMonitor) Check(ctx context.Context) error {
+	nodes := m.Nodes()
+	if len(nodes) == 0 {
+		return nil
+	}
+	// A failed listing must not be mistaken for deleted instances.
+	instances, err := m.Updater.GetInstancesFromVPC(ctx, m.Updater.instanceListURL(url.Values{"limit": {instanceListPageLimit}}))
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]*Instance, len(instances))
+	for _, instance := range instances {
+		byID[instance.ID] = instance
+	}
+
+	var failed int
+	for _, node := range nodes {
+		instanceID := node.Labels[instanceIDLabelKey]
+		if instanceID == "" {
+			continue
+		}
+		instance, ok := byID[instanceID]
+		if !ok {
+			if instance, err = m.Updater.getInstance(ctx, instanceID); err != nil && !errors.Is(err, errInstanceNotFound) {
+				m.Updater.Logger.Warn("Failed to get VPC instance of node", zap.String("workerNodeName", node.Name), zap.Error(err))
+				failed++
+				continue
+			}
+		}
+		status, reason, message := instanceHealth(instanceID, instance)
+		if err := m.setInstanceHealth(ctx, node, status, reason, message); err != nil {
+			m.Updater.Logger.Warn("Failed to update VPC instance health of node", zap.String("workerNodeName", node.Name), zap.Error(err))
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to check the VPC instance health of %d nodes", failed)
+	}
+	return nil
+}
+
+// setInstanceHealth updates the condition and the taint of the node if they don't match the instance health.
+func (m *InstanceHealthMonitor) setInstanceHealth(ctx context.Context, cached *v1.Node, status v1.ConditionStatus, reason, message string) error {
+	if !m.needsUpdate(cached, status, reason) {
+		return nil
+	}
+	logger := m.Updater.Logger.With(zap.String("workerNodeName", cached.Name), zap.String("reason", reason))
+	nodes := m.Updater.K8sClient.CoreV1().Nodes()
+	var node *v1.Node
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		if node, err = nodes.Get(ctx, cached.Name, metav1.GetOptions{}); err != nil {
+			return err
+		}
+		if !m.setTaint(node, status == v1.ConditionFalse) {
+			return nil
+		}
+		node, err = nodes.Update(ctx, node, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	var previous v1.ConditionStatus
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		if node == nil {
+			if node, err = nodes.Get(ctx, cached.Name, metav1.GetOptions{}); err != nil {
+				return err
+			}
+		}
+		previous = setNodeCondition(node, status, reason, message)
+		_, err = nodes.UpdateStatus(ctx, node, metav1.UpdateOptions{})
+		node = nil
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	updater := *m.Updater
+	updater.Node = cached
+	if status == v1.ConditionFalse {
+		logger.Warn("VPC instance of node is unhealthy", zap.String("message", message))
+		if previous != v1.ConditionFalse {
+			updater.recordEvent(v1.EventTypeWarning, eventReasonInstanceUnhealthy, "%s", message)
+		}
+		return nil
+	}
+	logger.Info("VPC instance of node is healthy")
+	if previous == v1.ConditionFalse {
+		updater.recordEvent(v1.EventTypeNormal, eventReasonInstanceRecovered, "%s", message)
+	}
+	return nil
+}
+
+// needsUpdate returns true if the condition or taint of the node doesn't match the instance health.
+func (m *InstanceHealthMonitor) needsUpdate(node *v1.Node, status v1.ConditionStatus, reason string) bool {
+	condition := findNodeCondition(node, InstanceHealthyConditionType)
+	if condition == nil || condition.Status != status || condition.Reason != reason {
+		return true
+	}
+	return m.setTaint(node.DeepCopy(), status == v1.ConditionFalse)
+}
+
+// setTaint adds the taint to the node, or removes it, and returns true if the taints changed.
+func (m *InstanceHealthMonitor) setTaint(node *v1.Node, tainted bool) bool {
+	if m.Taint == nil {
+		return false
+	}
+	for i, taint := range node.Spec.Taints {
+		if taint.MatchTaint(m.Taint) {
+			if tainted {
+				return false
+			}
+			node.Spec.Taints = append(node.Spec.Taints[:i], node.Spec.Taints[i+1:]...)
+			return true
+		}
+	}
+	if !tainted {
+		return false
+	}
+	taint := *m.Taint
+	now := metav1.Now()
+	taint.TimeAdded = &now
+	node.Spec.Taints = append(node.Spec.Taints, taint)
+	return true
+}
+
+// findNodeCondition returns the condition of the given type of the node, or nil.
+func findNodeCondition(node *v1.Node, conditionType v1.NodeConditionType) *v1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == conditionType {
+			return &node.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// setNodeCondition sets the VPCInstanceHealthy condition of the node, and returns its previous status,
+// empty if the node had no such condition.
+func setNodeCondition(node *v1.Node, status v1.ConditionStatus, reason, message string) v1.ConditionStatus {
+	now := metav1.Now()
+	condition := findNodeCondition(node, InstanceHealthyConditionType)
+	if condition == nil {
+		node.Status.Conditions = append(node.Status.Conditions, v1.NodeCondition{Type: InstanceHealthyConditionType})
+		condition = &node.Status.Conditions[len(node.Status.Conditions)-1]
+	}
+	previous := condition.Status
+	if previous != status {
+		condition.LastTransitionTime = now
+	}
+	condition.Status = status
+	condition.Reason = reason
+	condition.Message = message
+	condition.LastHeartbeatTime = now
+	return previous
+}
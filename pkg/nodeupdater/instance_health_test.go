@@ -0,0 +1,132 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseTaint(t *testing.T) {
+	testCases := []struct {
+		name     string
+		spec     string
+		expected *v1.Taint
+		expErr   bool
+	}{
+		{name: "Key and effect", spec: "vpc.ibm.com/instance-unhealthy:NoSchedule", expected: &v1.Taint{Key: "vpc.ibm.com/instance-unhealthy", Effect: v1.TaintEffectNoSchedule}},
+		{name: "Key, value and effect", spec: "instance=unhealthy:NoExecute", expected: &v1.Taint{Key: "instance", Value: "unhealthy", Effect: v1.TaintEffectNoExecute}},
+		{name: "No effect", spec: "instance", expErr: true},
+		{name: "Invalid effect", spec: "instance:Never", expErr: true},
+		{name: "No key", spec: ":NoSchedule", expErr: true},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		taint, err := ParseTaint(tc.spec)
+		if tc.expErr {
+			assert.NotNil(t, err)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expected, taint)
+	}
+}
+
+func TestInstanceHealthMonitor(t *testing.T) {
+	instances := testInstances()
+	instances[0].Status = "running"
+	instances[1].Status = "stopped"
+	requests := 0
+	server := newFakeRiaasServer(t, instances, &requests)
+
+	k8sClient := fake.NewSimpleClientset(
+		testNode("worker-1", map[string]string{instanceIDLabelKey: "instance-1"}),
+		testNode("worker-2", map[string]string{instanceIDLabelKey: "instance-2"}),
+		testNode("worker-gone", map[string]string{instanceIDLabelKey: "instance-gone"}),
+		testNode("worker-unlabeled", nil),
+	)
+	updater := initNodeLabelUpdater(t)
+	updater.K8sClient = k8sClient
+	updater.StorageSecretConfig.RiaasEndpointURL, _ = url.Parse(server.URL + "/v1/instances?generation=2")
+	taint, _ := ParseTaint(DefaultInstanceUnhealthyTaint)
+	monitor := &InstanceHealthMonitor{
+		Updater: updater,
+		Nodes: func() []*v1.Node {
+			list, _ := k8sClient.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+			nodes := make([]*v1.Node, 0, len(list.Items))
+			for i := range list.Items {
+				nodes = append(nodes, &list.Items[i])
+			}
+			return nodes
+		},
+		Taint: taint,
+	}
+
+	type nodeHealth struct {
+		status  v1.ConditionStatus
+		reason  string
+		tainted bool
+	}
+	getHealth := func(name string) nodeHealth {
+		node, _ := k8sClient.CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
+		health := nodeHealth{}
+		if condition := findNodeCondition(node, InstanceHealthyConditionType); condition != nil {
+			health.status, health.reason = condition.Status, condition.Reason
+		}
+		for _, nodeTaint := range node.Spec.Taints {
+			health.tainted = health.tainted || nodeTaint.MatchTaint(taint)
+		}
+		return health
+	}
+
+	assert.Nil(t, monitor.Check(context.TODO()))
+	assert.Equal(t, nodeHealth{status: v1.ConditionTrue, reason: instanceReasonRunning}, getHealth("worker-1"))
+	assert.Equal(t, nodeHealth{status: v1.ConditionFalse, reason: instanceReasonStopped, tainted: true}, getHealth("worker-2"))
+	assert.Equal(t, nodeHealth{status: v1.ConditionFalse, reason: instanceReasonNotFound, tainted: true}, getHealth("worker-gone"))
+	assert.Equal(t, nodeHealth{}, getHealth("worker-unlabeled"))
+
+	// Instances excluded by the instance list filter are got by ID and not considered deleted.
+	instances[0].Vpc = &Vpc{ID: "vpc-1"}
+	monitor.Updater.InstanceListFilter = InstanceListFilter{VPCID: "vpc-1"}
+	assert.Nil(t, monitor.Check(context.TODO()))
+	assert.Equal(t, nodeHealth{status: v1.ConditionTrue, reason: instanceReasonRunning}, getHealth("worker-1"))
+	assert.Equal(t, nodeHealth{status: v1.ConditionFalse, reason: instanceReasonStopped, tainted: true}, getHealth("worker-2"))
+	assert.Equal(t, nodeHealth{status: v1.ConditionFalse, reason: instanceReasonNotFound, tainted: true}, getHealth("worker-gone"))
+	monitor.Updater.InstanceListFilter = InstanceListFilter{}
+
+	// The recovered instance is untainted, unchanged nodes are not updated again.
+	instances[1].Status = "running"
+	k8sClient.ClearActions()
+	assert.Nil(t, monitor.Check(context.TODO()))
+	assert.Equal(t, nodeHealth{status: v1.ConditionTrue, reason: instanceReasonRunning}, getHealth("worker-2"))
+	assert.Equal(t, nodeHealth{status: v1.ConditionFalse, reason: instanceReasonNotFound, tainted: true}, getHealth("worker-gone"))
+	updates := 0
+	for _, action := range k8sClient.Actions() {
+		if action.GetVerb() == "update" {
+			updates++
+		}
+	}
+	// Untainting worker-2 and updating its condition.
+	assert.Equal(t, 2, updates)
+}
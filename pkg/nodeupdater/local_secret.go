@@ -0,0 +1,35 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import "go.uber.org/zap"
+
+// LocalSecretSource reads the VPC credentials and endpoints from the vpc section of a local slclient.toml file,
+// the content of the storage-secret-store secret, instead of the cluster. The file is read again on every call.
+func LocalSecretSource(file string, ctxLogger *zap.Logger) SecretConfigSource {
+	return func() (*StorageSecretConfig, error) {
+		credentials, err := readCredentialsFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if credentials, err = credentials.complete(); err != nil {
+			return nil, err
+		}
+		return newCredentialsSecretConfig(credentials, ctxLogger)
+	}
+}
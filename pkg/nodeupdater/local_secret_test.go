@@ -0,0 +1,39 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalSecretSource(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	_, err := LocalSecretSource(filepath.Join("..", "..", "test-fixtures", "missing.toml"), logger)()
+	assert.NotNil(t, err)
+
+	config, err := LocalSecretSource(filepath.Join("..", "..", "test-fixtures", "slclient.toml"), logger)()
+	assert.Nil(t, err)
+	assert.Equal(t, "us-south.iaas.cloud.ibm.com", config.RiaasEndpointURL.Host)
+	assert.Equal(t, "resource-group-id", config.ResourceGroupID)
+	assert.NotNil(t, config.TokenSource)
+}
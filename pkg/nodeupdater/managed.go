@@ -0,0 +1,218 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// ManagedLabelsAnnotationKey lists the label keys written by the updater and their previous values.
+	ManagedLabelsAnnotationKey = "vpc-node-label-updater.ibm-cloud.kubernetes.io/managed-labels"
+)
+
+// ManagedLabel records a label written by the updater, the value the node carried before, if any, and the
+// value last written.
+type ManagedLabel struct {
+	Previous *string `json:"previous,omitempty"`
+	// Value is empty for labels written by older releases.
+	Value string `json:"value,omitempty"`
+}
+
+// ManagedLabels maps the label keys written by the updater to their previous values.
+type ManagedLabels map[string]ManagedLabel
+
+// GetManagedLabels reads the managed labels annotation of the node.
+func GetManagedLabels(node *v1.Node) (ManagedLabels, error) {
+	managed := ManagedLabels{}
+	value, ok := node.ObjectMeta.Annotations[ManagedLabelsAnnotationKey]
+	if !ok {
+		return managed, nil
+	}
+	if err := json.Unmarshal([]byte(value), &managed); err != nil {
+		return ManagedLabels{}, fmt.Errorf("failed to unmarshal annotation %s of node %s: %v", ManagedLabelsAnnotationKey, node.Name, err)
+	}
+	return managed, nil
+}
+
+// setOn writes the managed labels annotation of the node.
+func (m ManagedLabels) setOn(node *v1.Node) error {
+	if len(m) == 0 {
+		delete(node.ObjectMeta.Annotations, ManagedLabelsAnnotationKey)
+		return nil
+	}
+	value, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if node.ObjectMeta.Annotations == nil {
+		node.ObjectMeta.Annotations = map[string]string{}
+	}
+	node.ObjectMeta.Annotations[ManagedLabelsAnnotationKey] = string(value)
+	return nil
+}
+
+// setLabel writes a label on the node, recording the value it replaces the first time the key is written.
+func (m ManagedLabels) setLabel(node *v1.Node, key, value string) {
+	managed, ok := m[key]
+	if !ok {
+		if previous, ok := node.ObjectMeta.Labels[key]; ok {
+			managed.Previous = &previous
+		}
+	}
+	managed.Value = value
+	m[key] = managed
+	node.ObjectMeta.Labels[key] = value
+}
+
+// wrote returns true if the label carries the value last written by the updater. Values of labels written by
+// older releases are assumed to be written by the updater.
+func (m ManagedLabels) wrote(key, value string) bool {
+	managed, ok := m[key]
+	return ok && (managed.Value == "" || managed.Value == value)
+}
+
+// adopt starts managing the expected labels already carrying the expected value, found on a node labeled before the
+// managed labels annotation was introduced. Their previous values are unknown, so cleaning up removes them. It returns
+// true if any label was adopted.
+func (m ManagedLabels) adopt(node *v1.Node, expected map[string]string) bool {
+	adopted := false
+	for key, value := range expected {
+		if _, ok := m[key]; ok {
+			continue
+		}
+		if node.ObjectMeta.Labels[key] == value {
+			m[key] = ManagedLabel{Value: value}
+			adopted = true
+		}
+	}
+	return adopted
+}
+
+// removeLabel removes a label from the node, which is no longer managed afterwards.
+func (m ManagedLabels) removeLabel(node *v1.Node, key string) {
+	delete(m, key)
+	delete(node.ObjectMeta.Labels, key)
+}
+
+// managedLabels reads the managed labels annotation of the updater's node. An unreadable annotation
+// is logged and replaced, as it would otherwise block labeling the node.
+func (c *VpcNodeLabelUpdater) managedLabels() ManagedLabels {
+	managed, err := GetManagedLabels(c.Node)
+	if err != nil {
+		c.Logger.Warn("Ignoring invalid managed labels annotation", zap.String("workerNodeName", c.Node.Name), zap.Error(err))
+	}
+	if c.Node.ObjectMeta.Labels == nil {
+		c.Node.ObjectMeta.Labels = map[string]string{}
+	}
+	return managed
+}
+
+// CleanupNodeLabels removes the labels listed in the managed labels annotation from the node, or restores
+// their previous values if restore is true, and removes the annotation and the annotations written from node
+// label policies. It returns false if the node carries no managed labels annotation.
+func CleanupNodeLabels(node *v1.Node, restore bool) (bool, error) {
+	if _, ok := node.ObjectMeta.Annotations[ManagedLabelsAnnotationKey]; !ok {
+		return false, nil
+	}
+	managed, err := GetManagedLabels(node)
+	if err != nil {
+		return false, err
+	}
+	if node.ObjectMeta.Labels == nil {
+		node.ObjectMeta.Labels = map[string]string{}
+	}
+	for key, label := range managed {
+		if restore && label.Previous != nil {
+			node.ObjectMeta.Labels[key] = *label.Previous
+			continue
+		}
+		delete(node.ObjectMeta.Labels, key)
+	}
+	// Annotations a policy took over get their previous value back.
+	keys := getPolicyKeys(node)
+	for _, key := range keys.Annotations {
+		if previous, ok := keys.PreviousAnnotations[key]; ok {
+			node.ObjectMeta.Annotations[key] = previous
+			continue
+		}
+		delete(node.ObjectMeta.Annotations, key)
+	}
+	delete(node.ObjectMeta.Annotations, PolicyKeysAnnotationKey)
+	delete(node.ObjectMeta.Annotations, ManagedLabelsAnnotationKey)
+	return true, nil
+}
+
+// CleanupOptions ...
+type CleanupOptions struct {
+	// Selector is a label selector restricting the nodes to clean up. Empty selects all nodes.
+	Selector string
+	// Restore restores the previous values of the managed labels instead of removing them.
+	Restore bool
+	// DryRun only logs the nodes which would be cleaned up.
+	DryRun bool
+}
+
+// CleanupNodes runs CleanupNodeLabels on all the selected nodes, and returns the number of nodes cleaned up.
+func CleanupNodes(ctx context.Context, k8sClient kubernetes.Interface, options CleanupOptions, logger *zap.Logger) (int, error) {
+	nodes, err := k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: options.Selector})
+	if err != nil {
+		return 0, err
+	}
+	cleaned := 0
+	for i := range nodes.Items {
+		nodeName := nodes.Items[i].Name
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			node, err := k8sClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			changed, err := CleanupNodeLabels(node, options.Restore)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				logger.Info("Node carries no labels managed by the updater, skipping", zap.String("node", nodeName))
+				return nil
+			}
+			if options.DryRun {
+				logger.Info("Dry run, not cleaning up node", zap.String("node", nodeName), zap.Reflect("labels", node.ObjectMeta.Labels))
+				cleaned++
+				return nil
+			}
+			if _, err = k8sClient.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{}); err != nil {
+				return err
+			}
+			logger.Info("Cleaned up node labels", zap.String("node", nodeName), zap.Bool("restored", options.Restore))
+			cleaned++
+			return nil
+		})
+		if err != nil {
+			return cleaned, fmt.Errorf("failed to clean up node %s: %v", nodeName, err)
+		}
+	}
+	return cleaned, nil
+}
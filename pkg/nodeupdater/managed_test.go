@@ -0,0 +1,117 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestManagedLabelsAnnotation(t *testing.T) {
+	updater := initCachedNodeLabelUpdater(t, testNode("worker-1", map[string]string{"test": "test", topologyZoneLabelKey: "kubelet-zone"}))
+	_, err := updater.UpdateNodeLabel(context.TODO(), "worker-1")
+	assert.Nil(t, err)
+
+	node, err := updater.K8sClient.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	managed, err := GetManagedLabels(node)
+	assert.Nil(t, err)
+	assert.Contains(t, managed, instanceIDLabelKey)
+	assert.Contains(t, managed, vpcBlockLabelKey)
+	assert.NotContains(t, managed, "test")
+	assert.Nil(t, managed[instanceIDLabelKey].Previous)
+	assert.Equal(t, "kubelet-zone", *managed[topologyZoneLabelKey].Previous)
+
+	// Labeling again keeps the value found before the first update.
+	updater.Node = node
+	_, err = updater.UpdateNodeLabel(context.TODO(), "worker-1")
+	assert.Nil(t, err)
+	managed, err = GetManagedLabels(updater.Node)
+	assert.Nil(t, err)
+	assert.Equal(t, "kubelet-zone", *managed[topologyZoneLabelKey].Previous)
+}
+
+func TestAdoptManagedLabels(t *testing.T) {
+	labels, _ := LabelSchemaOptions{}.desiredLabels(&NodeInfo{InstanceID: "instance-1", Region: "us-south", Zone: "us-south-1"})
+	testCases := []struct {
+		name       string
+		policy     DriftPolicy
+		expManaged int
+	}{
+		{name: "correct", policy: DriftPolicyCorrect, expManaged: len(labels)},
+		{name: "report leaves the node unchanged", policy: DriftPolicyReport},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		updater := initCachedNodeLabelUpdater(t, testNode("worker-1", labels))
+		updater.DriftPolicy = tc.policy
+		drifts, err := updater.VerifyNodeLabel(context.TODO(), "worker-1")
+		assert.Nil(t, err)
+		assert.Empty(t, drifts)
+
+		node, err := updater.K8sClient.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+		assert.Nil(t, err)
+		managed, err := GetManagedLabels(node)
+		assert.Nil(t, err)
+		assert.Len(t, managed, tc.expManaged)
+	}
+}
+
+func TestCleanupNodeLabelsWithoutLabels(t *testing.T) {
+	previous := "kubelet-zone"
+	node := testNode("worker-1", nil)
+	assert.Nil(t, ManagedLabels{topologyZoneLabelKey: {Previous: &previous}}.setOn(node))
+	cleaned, err := CleanupNodeLabels(node, true)
+	assert.Nil(t, err)
+	assert.True(t, cleaned)
+	assert.Equal(t, map[string]string{topologyZoneLabelKey: "kubelet-zone"}, node.Labels)
+}
+
+func TestCleanupNodes(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	testCases := []struct {
+		name      string
+		options   CleanupOptions
+		expZone   string
+		expLabels int
+	}{
+		{name: "remove", options: CleanupOptions{}, expLabels: 1},
+		{name: "restore", options: CleanupOptions{Restore: true}, expZone: "kubelet-zone", expLabels: 2},
+		{name: "dry run", options: CleanupOptions{DryRun: true}, expZone: "us-south-1", expLabels: 9},
+		{name: "selector", options: CleanupOptions{Selector: "pool=other"}, expZone: "us-south-1", expLabels: 9},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		updater := initCachedNodeLabelUpdater(t, testNode("worker-1", map[string]string{"test": "test", topologyZoneLabelKey: "kubelet-zone"}))
+		_, err := updater.UpdateNodeLabel(context.TODO(), "worker-1")
+		assert.Nil(t, err)
+
+		_, err = CleanupNodes(context.TODO(), updater.K8sClient, tc.options, logger)
+		assert.Nil(t, err)
+		node, err := updater.K8sClient.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expZone, node.Labels[topologyZoneLabelKey])
+		assert.Equal(t, "test", node.Labels["test"])
+		assert.Len(t, node.Labels, tc.expLabels)
+	}
+}
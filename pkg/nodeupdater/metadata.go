@@ -0,0 +1,189 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultMetadataEndpointURL is the VPC metadata service, reachable from the instances only.
+	DefaultMetadataEndpointURL = "http://api.metadata.cloud.ibm.com"
+	metadataVersion            = "2022-03-01"
+	// instanceIdentityTokenLifetime is the lifetime in seconds requested for the instance identity token, which
+	// is only used for the IAM token exchange.
+	instanceIdentityTokenLifetime = 300
+)
+
+// MetadataConfig configures the IAM token of the instance identity token.
+type MetadataConfig struct {
+	// EndpointURL is the metadata service, DefaultMetadataEndpointURL if empty.
+	EndpointURL string
+	// TrustedProfileID is the trusted profile the token is issued for. Empty uses the profile linked to the instance.
+	TrustedProfileID string
+	// RiaasEndpointURL overrides the public RIAAS endpoint of the region of the instance.
+	RiaasEndpointURL string
+}
+
+// metadataToken is the response of the token requests of the metadata service.
+type metadataToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// MetadataSource sets up the secret configuration from the VPC metadata service of the instance the updater runs
+// on: the instance identity token is exchanged for an IAM token of the trusted profile linked to the instance, no
+// secret is needed. If the metadata service can't issue a token, the secret configuration of fallback is used.
+func MetadataSource(config MetadataConfig, fallback SecretConfigSource, ctxLogger *zap.Logger) SecretConfigSource {
+	return func() (*StorageSecretConfig, error) {
+		storageSecretConfig, err := newMetadataSecretConfig(config, ctxLogger)
+		if err != nil {
+			ctxLogger.Warn("Failed to get IAM token from the metadata service, reading the secret configuration", zap.Error(err))
+			return fallback()
+		}
+		ctxLogger.Info("Using IAM token of the instance identity", zap.String("riaasEndpoint", storageSecretConfig.RiaasEndpointURL.Host))
+		return storageSecretConfig, nil
+	}
+}
+
+// newMetadataSecretConfig sets up the metadata token source and fetches the first token, to fall back early
+// when the metadata service or the trusted profile are not available.
+func newMetadataSecretConfig(config MetadataConfig, ctxLogger *zap.Logger) (*StorageSecretConfig, error) {
+	client := &metadataClient{endpointURL: strings.TrimSuffix(config.EndpointURL, "/"), trustedProfileID: config.TrustedProfileID}
+	if client.endpointURL == "" {
+		client.endpointURL = DefaultMetadataEndpointURL
+	}
+	riaasURL := config.RiaasEndpointURL
+	if riaasURL == "" {
+		region, err := client.region()
+		if err != nil {
+			return nil, err
+		}
+		riaasURL = fmt.Sprintf("https://%s.iaas.cloud.ibm.com", region)
+	}
+	endpoint, err := normalizeEndpointURL(riaasURL, ctxLogger)
+	if err != nil {
+		return nil, err
+	}
+	storageSecretConfig := &StorageSecretConfig{
+		RiaasEndpointURL: instancesURL(endpoint),
+		TokenSource:      NewTokenSource(client.iamToken),
+	}
+	if _, err := storageSecretConfig.TokenSource.Token(context.Background()); err != nil {
+		return nil, err
+	}
+	return storageSecretConfig, nil
+}
+
+// metadataClient requests tokens and the instance of the metadata service.
+type metadataClient struct {
+	endpointURL      string
+	trustedProfileID string
+}
+
+// identityToken returns a new instance identity token.
+func (m *metadataClient) identityToken() (string, error) {
+	body := fmt.Sprintf(`{"expires_in": %d}`, instanceIdentityTokenLifetime)
+	var token metadataToken
+	err := m.do(http.MethodPut, "/instance_identity/v1/token", map[string]string{"Metadata-Flavor": "ibm"}, []byte(body), &token)
+	if err != nil {
+		return "", fmt.Errorf("failed to get instance identity token: %v", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("metadata service returned no instance identity token")
+	}
+	return token.AccessToken, nil
+}
+
+// iamToken exchanges a new instance identity token for an IAM token of the trusted profile.
+func (m *metadataClient) iamToken() (string, uint64, error) {
+	identityToken, err := m.identityToken()
+	if err != nil {
+		return "", 0, err
+	}
+	body := []byte("{}")
+	if m.trustedProfileID != "" {
+		if body, err = json.Marshal(map[string]interface{}{"trusted_profile": map[string]string{"id": m.trustedProfileID}}); err != nil {
+			return "", 0, err
+		}
+	}
+	var token metadataToken
+	err = m.do(http.MethodPost, "/instance_identity/v1/iam_token", map[string]string{"Authorization": "Bearer " + identityToken}, body, &token)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to exchange instance identity token for IAM token: %v", err)
+	}
+	if token.AccessToken == "" || token.ExpiresIn <= 0 {
+		return "", 0, errors.New("metadata service returned no IAM token")
+	}
+	return token.AccessToken, uint64(token.ExpiresIn), nil
+}
+
+// region returns the region of the instance, from its zone.
+func (m *metadataClient) region() (string, error) {
+	identityToken, err := m.identityToken()
+	if err != nil {
+		return "", err
+	}
+	var instance Instance
+	if err := m.do(http.MethodGet, "/metadata/v1/instance", map[string]string{"Authorization": "Bearer " + identityToken}, nil, &instance); err != nil {
+		return "", fmt.Errorf("failed to get instance metadata: %v", err)
+	}
+	if instance.Zone == nil || strings.LastIndex(instance.Zone.Name, "-") <= 0 {
+		return "", errors.New("instance metadata has no zone")
+	}
+	return instance.Zone.Name[:strings.LastIndex(instance.Zone.Name, "-")], nil
+}
+
+// do sends a request to the metadata service and decodes the JSON response into result.
+func (m *metadataClient) do(method, path string, headers map[string]string, body []byte, result interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), iamRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, m.endpointURL+path+"?version="+metadataVersion, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, result)
+}
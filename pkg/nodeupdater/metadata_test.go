@@ -0,0 +1,125 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMetadataService serves the token and instance requests of the VPC metadata service. The IAM token is only
+// issued for the trusted profile linked to the instance, Profile-1.
+func fakeMetadataService(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, metadataVersion, r.URL.Query().Get("version"))
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/instance_identity/v1/token":
+			if r.Header.Get("Metadata-Flavor") != "ibm" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"identity-token","expires_in":300}`))
+			return
+		case r.Header.Get("Authorization") != "Bearer identity-token":
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		case r.Method == http.MethodGet && r.URL.Path == "/metadata/v1/instance":
+			_, _ = w.Write([]byte(`{"id":"instance-1","zone":{"name":"eu-de-2"}}`))
+			return
+		case r.Method == http.MethodPost && r.URL.Path == "/instance_identity/v1/iam_token":
+			var body struct {
+				TrustedProfile *struct {
+					ID string `json:"id"`
+				} `json:"trusted_profile"`
+			}
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+			if body.TrustedProfile != nil && body.TrustedProfile.ID != "Profile-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":[{"code":"invalid_trusted_profile"}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"iam-token","expires_in":3600}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+}
+
+func TestMetadataSource(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+	server := fakeMetadataService(t)
+	defer server.Close()
+
+	fallbackConfig := &StorageSecretConfig{RiaasEndpointURL: &url.URL{Host: "fallback"}, TokenSource: NewTokenSource(func() (string, uint64, error) {
+		return "fallback-token", 3600, nil
+	})}
+	fallback := func() (*StorageSecretConfig, error) { return fallbackConfig, nil }
+
+	testCases := []struct {
+		name         string
+		config       MetadataConfig
+		expToken     string
+		expRiaasHost string
+	}{
+		{
+			name:         "Linked trusted profile",
+			config:       MetadataConfig{EndpointURL: server.URL},
+			expToken:     "iam-token",
+			expRiaasHost: "eu-de.iaas.cloud.ibm.com",
+		},
+		{
+			name:         "Trusted profile and RIAAS endpoint set",
+			config:       MetadataConfig{EndpointURL: server.URL + "/", TrustedProfileID: "Profile-1", RiaasEndpointURL: "https://eu-de.private.iaas.cloud.ibm.com"},
+			expToken:     "iam-token",
+			expRiaasHost: "eu-de.private.iaas.cloud.ibm.com",
+		},
+		{
+			name:         "Trusted profile not linked",
+			config:       MetadataConfig{EndpointURL: server.URL, TrustedProfileID: "Profile-2"},
+			expToken:     "fallback-token",
+			expRiaasHost: "fallback",
+		},
+		{
+			name:         "Metadata service not reachable",
+			config:       MetadataConfig{EndpointURL: "http://127.0.0.1:1"},
+			expToken:     "fallback-token",
+			expRiaasHost: "fallback",
+		},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		config, err := ReadSecretConfigurationFrom(context.TODO(), MetadataSource(tc.config, fallback, logger), logger)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expToken, config.IAMAccessToken)
+		assert.Equal(t, tc.expRiaasHost, config.RiaasEndpointURL.Host)
+	}
+
+	// Errors of the fallback are returned.
+	_, err := MetadataSource(MetadataConfig{EndpointURL: "http://127.0.0.1:1"}, func() (*StorageSecretConfig, error) {
+		return nil, errors.New("no secret")
+	}, logger)()
+	assert.EqualError(t, err, "no secret")
+}
@@ -0,0 +1,44 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "vpc_node_label_updater"
+
+var (
+	// labelConflictsTotal counts the labels found with a value set by someone else than the updater.
+	labelConflictsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "label_conflicts_total",
+		Help:      "Number of node labels found with a value different from the one resolved from VPC, by label key and conflict policy.",
+	}, []string{"label", "policy"})
+
+	// secretConfigReloadsTotal counts the reloads of the secret configuration.
+	secretConfigReloadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "secret_config_reloads_total",
+		Help:      "Number of reloads of the secret configuration after the secret or the cluster-info configmap changed, by result.",
+	}, []string{"result"})
+)
+
+func init() {
+	prometheus.MustRegister(labelConflictsTotal, secretConfigReloadsTotal)
+}
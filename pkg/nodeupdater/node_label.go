@@ -20,11 +20,13 @@ package nodeupdater
 import (
 	"context"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 )
 
 // VpcNodeLabelUpdater ...
@@ -33,27 +35,58 @@ type VpcNodeLabelUpdater struct {
 	K8sClient           kubernetes.Interface
 	Logger              *zap.Logger
 	StorageSecretConfig *StorageSecretConfig
+	// InstanceCache is optional. When set, worker details are looked up in the cache
+	// instead of querying VPC for every node.
+	InstanceCache *InstanceCache
+	// DriftPolicy decides whether VerifyNodeLabel corrects drifted labels or only reports them.
+	DriftPolicy DriftPolicy
+	// LabelSchemaOptions controls which legacy labels are written, kept or removed.
+	LabelSchemaOptions LabelSchemaOptions
+	// ConflictPolicies decides what happens to labels already set to a different value by someone else.
+	ConflictPolicies LabelConflictPolicies
+	// Recorder is optional. When set, label corrections and drift are recorded as node events.
+	Recorder record.EventRecorder
+	// SnapshotStore is optional. When set, the labels of the node are saved before they are changed
+	// so that they can be rolled back.
+	SnapshotStore SnapshotStore
+	// Policies is optional. When set, it returns the node label policies whose labels and annotations are
+	// applied along with the labels of the label schema.
+	Policies func() *PolicySet
+	// CurrentSecretConfig is optional. When set, it returns the secret configuration used instead of
+	// StorageSecretConfig, which can be replaced while the updater runs.
+	CurrentSecretConfig func() *StorageSecretConfig
+	// InstanceListFilter narrows the instance list queries to the instances of the cluster.
+	InstanceListFilter InstanceListFilter
+}
+
+// SecretConfig returns the secret configuration of the VPC requests.
+func (c *VpcNodeLabelUpdater) SecretConfig() *StorageSecretConfig {
+	if c.CurrentSecretConfig != nil {
+		return c.CurrentSecretConfig()
+	}
+	return c.StorageSecretConfig
 }
 
 // UpdateNodeLabel gets the details of the newly added node from riaas and updates the labels.
 // Returns false and err as nil if labels not updated. else returns true
 func (c *VpcNodeLabelUpdater) UpdateNodeLabel(ctx context.Context, workerNodeName string) (done bool, err error) {
-	nodeinfo, err := c.GetWorkerDetails(workerNodeName)
+	ctx, span := StartSpan(ctx, "UpdateNodeLabel")
+	defer func() { EndSpan(span, err) }()
+
+	nodeinfo, err := c.GetWorkerDetails(ctx, workerNodeName)
 	if err != nil {
 		return false, err
 	}
 
-	// Are adding both worker-id and instance-id label to satisfy all environements.
-	// TODO: remove worker-id label after its dependence is removed.
-	c.Node.ObjectMeta.Labels[workerIDLabelKey] = nodeinfo.InstanceID
-	c.Node.ObjectMeta.Labels[instanceIDLabelKey] = nodeinfo.InstanceID
-	c.Node.ObjectMeta.Labels[failureRegionLabelKey] = nodeinfo.Region
-	c.Node.ObjectMeta.Labels[failureZoneLabelKey] = nodeinfo.Zone
-	c.Node.ObjectMeta.Labels[topologyRegionLabelKey] = nodeinfo.Region
-	c.Node.ObjectMeta.Labels[topologyZoneLabelKey] = nodeinfo.Zone
-	c.Node.ObjectMeta.Labels[vpcBlockLabelKey] = "true"
-
-	_, err = c.K8sClient.CoreV1().Nodes().Update(ctx, c.Node, metav1.UpdateOptions{})
+	if version := LabelSchemaVersion(c.Node.ObjectMeta.Labels); version != 0 && version < CurrentLabelSchemaVersion {
+		c.Logger.Info("Migrating node labels to current label schema", zap.Reflect("workerNodeName", workerNodeName),
+			zap.Int("fromVersion", version), zap.Int("toVersion", CurrentLabelSchemaVersion))
+	}
+	snapshot := newLabelSnapshot(c.Node)
+	if err = c.applyNodeLabels(nodeinfo); err != nil {
+		return false, err
+	}
+	err = c.updateNodeWithSnapshot(ctx, snapshot)
 	if err == nil && !errors.IsConflict(err) {
 		c.Logger.Info("Added required labels for the node, ", zap.Reflect("workerNodeName", workerNodeName))
 		return true, nil
@@ -61,3 +94,75 @@ func (c *VpcNodeLabelUpdater) UpdateNodeLabel(ctx context.Context, workerNodeNam
 
 	return false, err
 }
+
+// applyNodeLabels sets the labels of the current schema and of the node label policies for the node details on
+// the updater's node, subject to the conflict policies, removes the retired labels and records them in the
+// managed labels annotation.
+func (c *VpcNodeLabelUpdater) applyNodeLabels(nodeinfo *NodeInfo) error {
+	managed := c.managedLabels()
+	labels, retired, policies := c.expectedLabels(c.Node, nodeinfo)
+	for key, value := range labels {
+		apply, err := c.resolveLabelConflict(managed, key, value)
+		if err != nil {
+			return err
+		}
+		if apply {
+			managed.setLabel(c.Node, key, value)
+		}
+	}
+	for _, key := range retired {
+		managed.removeLabel(c.Node, key)
+	}
+	if _, err := c.applyPolicyAnnotations(managed, policies); err != nil {
+		return err
+	}
+	return managed.setOn(c.Node)
+}
+
+// ReconcileNodeLabel labels the node if it was not labeled yet or carries labels of an older schema,
+// and verifies the label values otherwise.
+func (c *VpcNodeLabelUpdater) ReconcileNodeLabel(ctx context.Context, workerNodeName string) (err error) {
+	ctx, span := StartSpan(ctx, "ReconcileNodeLabel", attribute.String("node", workerNodeName))
+	defer func() { EndSpan(span, err) }()
+
+	if isNodeLabeled(c.Node.ObjectMeta.Labels) {
+		c.Logger.Info("Required labels already present on the worker node, verifying label values", zap.String("workerNodeName", workerNodeName))
+		_, err = c.VerifyNodeLabel(ctx, workerNodeName)
+		return err
+	}
+	_, err = c.UpdateNodeLabel(ctx, workerNodeName)
+	return err
+}
+
+// ReconcileNode sets up an updater with setup and reconciles the labels of the node. On a node labeled already,
+// failing to set up the updater, e.g. to read the secret configuration or the IAM token, or to verify the labels
+// is only logged, so that the pods of the node are not kept from starting. The updater is nil if setup failed.
+func ReconcileNode(ctx context.Context, node *v1.Node, setup func() (*VpcNodeLabelUpdater, error), logger *zap.Logger) (*VpcNodeLabelUpdater, error) {
+	labeled := isNodeLabeled(node.ObjectMeta.Labels)
+	c, err := setup()
+	if err != nil && labeled {
+		logger.Warn("Failed to set up the verification of the labels of the node", zap.String("workerNodeName", node.Name), zap.Error(err))
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	c.Node = node
+	if err = c.ReconcileNodeLabel(ctx, node.Name); err != nil && labeled {
+		logger.Warn("Failed to verify the labels of the node", zap.String("workerNodeName", node.Name), zap.Error(err))
+		return c, nil
+	}
+	return c, err
+}
+
+// isNodeLabeled returns true if the node carries the labels of the current schema.
+func isNodeLabeled(labelMap map[string]string) bool {
+	return CheckIfRequiredLabelsPresent(labelMap) && !NeedsLabelSchemaMigration(labelMap)
+}
+
+// updateNode writes the labels and annotations of the updater's node.
+func (c *VpcNodeLabelUpdater) updateNode(ctx context.Context) (err error) {
+	ctx, span := StartSpan(ctx, "UpdateNode")
+	defer func() { EndSpan(span, err) }()
+	_, err = c.K8sClient.CoreV1().Nodes().Update(ctx, c.Node, metav1.UpdateOptions{})
+	return err
+}
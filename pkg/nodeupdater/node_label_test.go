@@ -87,3 +87,37 @@ func TestUpdateNodeLabel(t *testing.T) {
 		}
 	}
 }
+
+func TestReconcileNode(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	labeled, _ := LabelSchemaOptions{}.desiredLabels(&NodeInfo{InstanceID: "instance-1", Region: "us-south", Zone: "us-south-1"})
+	testCases := []struct {
+		name       string
+		labels     map[string]string
+		setupErr   error
+		expErr     bool
+		expUpdater bool
+	}{
+		{name: "Labeled node without secret configuration", labels: labeled, setupErr: errors.New("IAM token exchange failed")},
+		{name: "Unlabeled node without secret configuration", labels: map[string]string{}, setupErr: errors.New("IAM token exchange failed"), expErr: true},
+		{name: "Labeled node", labels: labeled, expUpdater: true},
+		{name: "Unlabeled node", labels: map[string]string{}, expUpdater: true},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		node := testNode("worker-1", tc.labels)
+		updater, err := ReconcileNode(context.TODO(), node, func() (*VpcNodeLabelUpdater, error) {
+			if tc.setupErr != nil {
+				return nil, tc.setupErr
+			}
+			return initCachedNodeLabelUpdater(t, node), nil
+		}, logger)
+		assert.Equal(t, tc.expErr, err != nil)
+		assert.Equal(t, tc.expUpdater, updater != nil)
+		if updater != nil {
+			assert.True(t, isNodeLabeled(updater.Node.Labels))
+		}
+	}
+}
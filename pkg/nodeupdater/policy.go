@@ -0,0 +1,629 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// PolicyKeysAnnotationKey lists the label and annotation keys written on the node from node label policies.
+	PolicyKeysAnnotationKey = "vpc-node-label-updater.ibm-cloud.kubernetes.io/policy-keys"
+
+	// PolicyConditionAccepted is true if the policy is valid and applied to the nodes it selects.
+	PolicyConditionAccepted = "Accepted"
+	// PolicyConditionOverridden is true if keys of the policy are set by higher precedence policies on some of
+	// the nodes it selects.
+	PolicyConditionOverridden = "Overridden"
+
+	policyReasonValid          = "Valid"
+	policyReasonInvalid        = "Invalid"
+	policyReasonOverridden     = "KeysOverridden"
+	policyReasonNotOverridden  = "NoKeysOverridden"
+	updaterAnnotationKeyPrefix = "vpc-node-label-updater.ibm-cloud.kubernetes.io/"
+
+	// policyStatusInterval is how often the status of the policies is refreshed, as the nodes they select change.
+	policyStatusInterval = time.Minute
+)
+
+// NodeLabelPolicyResource is the cluster-scoped NodeLabelPolicy custom resource, defined by deploy/crd.yaml.
+var NodeLabelPolicyResource = schema.GroupVersionResource{
+	Group:    "vpc-node-label-updater.ibm-cloud.kubernetes.io",
+	Version:  "v1alpha1",
+	Resource: "nodelabelpolicies",
+}
+
+// NodeLabelPolicy sets labels and annotations rendered from the VPC instance on the nodes it selects.
+type NodeLabelPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NodeLabelPolicySpec   `json:"spec"`
+	Status NodeLabelPolicyStatus `json:"status,omitempty"`
+}
+
+// NodeLabelPolicySpec ...
+type NodeLabelPolicySpec struct {
+	// NodeSelector selects the nodes by their labels, those written from policies excluded. Empty selects all nodes.
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+	// Priority orders the policies setting the same key on a node, the highest priority wins. Ties are
+	// broken by policy name.
+	Priority int32 `json:"priority,omitempty"`
+	// Labels and Annotations map keys to text/template values rendered with PolicyTemplateData.
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// NodeLabelPolicyStatus ...
+type NodeLabelPolicyStatus struct {
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+	MatchedNodes       int32              `json:"matchedNodes"`
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// PolicyTemplateData is the data the label and annotation templates of the policies are rendered with.
+type PolicyTemplateData struct {
+	NodeName string
+	Region   string
+	Zone     string
+	// Instance holds the fields of the instance kept in the instance cache: ID, Name, CRN, Status, Memory,
+	// Vcpu, Zone, Profile, Vpc, ResourceGroup, Image.ID, Image.Name and PrimaryNetworkInterface.PrimaryIpv4Address.
+	// The other fields are empty, whether the instance was listed or read from a persisted cache.
+	Instance *Instance
+}
+
+// policyTemplateFuncs are the functions available to the policy templates, on top of the text/template builtins.
+var policyTemplateFuncs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+}
+
+// compiledPolicy is a policy with its parsed selector and templates. Invalid policies carry the error and are not applied.
+type compiledPolicy struct {
+	policy      *NodeLabelPolicy
+	selector    labels.Selector
+	labels      map[string]*template.Template
+	annotations map[string]*template.Template
+	err         error
+}
+
+func compilePolicy(policy *NodeLabelPolicy) *compiledPolicy {
+	compiled := &compiledPolicy{policy: policy, selector: labels.Everything()}
+	if policy.Spec.NodeSelector != nil {
+		if compiled.selector, compiled.err = metav1.LabelSelectorAsSelector(policy.Spec.NodeSelector); compiled.err != nil {
+			compiled.err = fmt.Errorf("invalid node selector: %v", compiled.err)
+			return compiled
+		}
+	}
+	if compiled.labels, compiled.err = compileTemplates("label", policy.Spec.Labels); compiled.err != nil {
+		return compiled
+	}
+	compiled.annotations, compiled.err = compileTemplates("annotation", policy.Spec.Annotations)
+	return compiled
+}
+
+// compileTemplates validates the keys and parses the value templates of the labels or annotations of a policy.
+func compileTemplates(kind string, values map[string]string) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template, len(values))
+	for key, value := range values {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid %s key %q: %s", kind, key, strings.Join(errs, "; "))
+		}
+		if isReservedPolicyKey(key) {
+			return nil, fmt.Errorf("%s key %q is reserved for the updater or Kubernetes", kind, key)
+		}
+		tmpl, err := template.New(key).Funcs(policyTemplateFuncs).Option("missingkey=error").Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template of %s %q: %v", kind, key, err)
+		}
+		templates[key] = tmpl
+	}
+	return templates, nil
+}
+
+// reservedPolicyKeyDomains are the key prefix domains reserved for Kubernetes components, with their subdomains,
+// e.g. kubernetes.io/hostname, topology.kubernetes.io/zone or node-role.kubernetes.io/control-plane.
+var reservedPolicyKeyDomains = []string{"kubernetes.io", "k8s.io"}
+
+// isReservedPolicyKey returns true for the keys of the label schema and the annotations of the updater, and the
+// keys of the Kubernetes domains, which policies can't set.
+func isReservedPolicyKey(key string) bool {
+	switch key {
+	case workerIDLabelKey, instanceIDLabelKey, failureRegionLabelKey, failureZoneLabelKey,
+		topologyRegionLabelKey, topologyZoneLabelKey, vpcBlockLabelKey, labelSchemaVersionKey:
+		return true
+	}
+	if strings.HasPrefix(key, updaterAnnotationKeyPrefix) {
+		return true
+	}
+	prefix, _, ok := strings.Cut(key, "/")
+	if !ok {
+		return false
+	}
+	for _, domain := range reservedPolicyKeyDomains {
+		if prefix == domain || strings.HasSuffix(prefix, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicySet holds the node label policies ordered by precedence: decreasing priority, then name.
+type PolicySet struct {
+	policies []*compiledPolicy
+}
+
+// NewPolicySet compiles the policies. Invalid policies are kept to report their error in their status.
+func NewPolicySet(policies []*NodeLabelPolicy) *PolicySet {
+	set := &PolicySet{}
+	for _, policy := range policies {
+		set.policies = append(set.policies, compilePolicy(policy))
+	}
+	sort.Slice(set.policies, func(i, j int) bool {
+		pi, pj := set.policies[i].policy, set.policies[j].policy
+		if pi.Spec.Priority != pj.Spec.Priority {
+			return pi.Spec.Priority > pj.Spec.Priority
+		}
+		return pi.Name < pj.Name
+	})
+	return set
+}
+
+// PolicyResult holds the labels and annotations the policies set on a node.
+type PolicyResult struct {
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// Evaluate renders the labels and annotations of the valid policies selecting the node. For each key, the
+// first policy by precedence whose template renders a valid value wins. Rendering errors are returned, the
+// keys failing to render are left to lower precedence policies.
+func (s *PolicySet) Evaluate(node *v1.Node, nodeinfo *NodeInfo) (*PolicyResult, []error) {
+	result := &PolicyResult{Labels: map[string]string{}, Annotations: map[string]string{}}
+	data := PolicyTemplateData{NodeName: node.Name, Region: nodeinfo.Region, Zone: nodeinfo.Zone, Instance: compactInstance(nodeinfo.Instance)}
+	nodeLabels := selectionLabels(node)
+	var errs []error
+	for _, policy := range s.policies {
+		if policy.err != nil || !policy.selector.Matches(nodeLabels) {
+			continue
+		}
+		for key, tmpl := range policy.labels {
+			if _, ok := result.Labels[key]; ok {
+				continue
+			}
+			value, err := renderPolicyTemplate(tmpl, data)
+			if err == nil {
+				if invalid := validation.IsValidLabelValue(value); len(invalid) > 0 {
+					err = fmt.Errorf("invalid value %q: %s", value, strings.Join(invalid, "; "))
+				}
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("policy %s, label %s: %v", policy.policy.Name, key, err))
+				continue
+			}
+			result.Labels[key] = value
+		}
+		for key, tmpl := range policy.annotations {
+			if _, ok := result.Annotations[key]; ok {
+				continue
+			}
+			value, err := renderPolicyTemplate(tmpl, data)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("policy %s, annotation %s: %v", policy.policy.Name, key, err))
+				continue
+			}
+			result.Annotations[key] = value
+		}
+	}
+	return result, errs
+}
+
+func renderPolicyTemplate(tmpl *template.Template, data PolicyTemplateData) (string, error) {
+	var value bytes.Buffer
+	if err := tmpl.Execute(&value, data); err != nil {
+		return "", err
+	}
+	return value.String(), nil
+}
+
+// selectionLabels returns the labels the policy selectors are matched against: the node labels except those
+// written from policies, so that policies can't select each other's nodes and flap.
+func selectionLabels(node *v1.Node) labels.Set {
+	selection := labels.Set{}
+	for key, value := range node.Labels {
+		selection[key] = value
+	}
+	for _, key := range getPolicyKeys(node).Labels {
+		delete(selection, key)
+	}
+	return selection
+}
+
+// policyStatus computes the status of a policy over the nodes, updating the conditions of its current status.
+func (s *PolicySet) policyStatus(policy *compiledPolicy, current NodeLabelPolicyStatus, nodes []*v1.Node) NodeLabelPolicyStatus {
+	generation := policy.policy.Generation
+	status := NodeLabelPolicyStatus{ObservedGeneration: generation}
+	status.Conditions = append(status.Conditions, current.Conditions...)
+	if policy.err != nil {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: PolicyConditionAccepted, Status: metav1.ConditionFalse,
+			Reason: policyReasonInvalid, Message: policy.err.Error(), ObservedGeneration: generation})
+		meta.RemoveStatusCondition(&status.Conditions, PolicyConditionOverridden)
+		return status
+	}
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: PolicyConditionAccepted, Status: metav1.ConditionTrue,
+		Reason: policyReasonValid, Message: "The policy is applied to the nodes it selects", ObservedGeneration: generation})
+
+	overridden, overriders := map[string]bool{}, map[string]bool{}
+	for _, node := range nodes {
+		nodeLabels := selectionLabels(node)
+		if !policy.selector.Matches(nodeLabels) {
+			continue
+		}
+		status.MatchedNodes++
+		for _, other := range s.policies {
+			if other == policy {
+				break
+			}
+			if other.err != nil || !other.selector.Matches(nodeLabels) {
+				continue
+			}
+			for key := range policy.labels {
+				if _, ok := other.labels[key]; ok {
+					overridden["label "+key], overriders[other.policy.Name] = true, true
+				}
+			}
+			for key := range policy.annotations {
+				if _, ok := other.annotations[key]; ok {
+					overridden["annotation "+key], overriders[other.policy.Name] = true, true
+				}
+			}
+		}
+	}
+	if len(overridden) == 0 {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: PolicyConditionOverridden, Status: metav1.ConditionFalse,
+			Reason: policyReasonNotOverridden, Message: "No key of the policy is set by a higher precedence policy", ObservedGeneration: generation})
+		return status
+	}
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: PolicyConditionOverridden, Status: metav1.ConditionTrue,
+		Reason: policyReasonOverridden, ObservedGeneration: generation,
+		Message: fmt.Sprintf("%s overridden by higher precedence policies %s on some of the selected nodes",
+			strings.Join(sortedKeys(overridden), ", "), strings.Join(sortedKeys(overriders), ", "))})
+	return status
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// policyKeys lists the label and annotation keys written on a node from policies, to remove them once no
+// policy sets them anymore.
+type policyKeys struct {
+	Labels      []string `json:"labels,omitempty"`
+	Annotations []string `json:"annotations,omitempty"`
+	// PreviousAnnotations holds the values of the annotations set before a policy took them over, restored once
+	// no policy sets them anymore.
+	PreviousAnnotations map[string]string `json:"previousAnnotations,omitempty"`
+}
+
+// getPolicyKeys reads the policy keys annotation of the node. An unreadable annotation is ignored.
+func getPolicyKeys(node *v1.Node) policyKeys {
+	var keys policyKeys
+	if value, ok := node.Annotations[PolicyKeysAnnotationKey]; ok {
+		_ = json.Unmarshal([]byte(value), &keys) // #nosec G104: an invalid annotation is rewritten on the next update.
+	}
+	return keys
+}
+
+// setOn writes the policy keys annotation of the node.
+func (k policyKeys) setOn(node *v1.Node) {
+	if len(k.Labels) == 0 && len(k.Annotations) == 0 {
+		delete(node.Annotations, PolicyKeysAnnotationKey)
+		return
+	}
+	value, _ := json.Marshal(k) // #nosec G104: string slices always marshal.
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	node.Annotations[PolicyKeysAnnotationKey] = string(value)
+}
+
+// PolicyWatcher watches the NodeLabelPolicy resources and keeps their status up to date.
+type PolicyWatcher struct {
+	Client dynamic.Interface
+	Logger *zap.Logger
+	// Nodes returns the nodes over which the status of the policies is computed.
+	Nodes func() []*v1.Node
+	// OnChange is optional, it is called when the policies change once synced, e.g. to reconcile all nodes.
+	OnChange func()
+
+	informer cache.SharedIndexInformer
+	policies atomic.Pointer[PolicySet]
+	changed  chan struct{}
+}
+
+// NewPolicyWatcher returns a watcher of the NodeLabelPolicy resources.
+func NewPolicyWatcher(client dynamic.Interface, logger *zap.Logger, resyncPeriod time.Duration) *PolicyWatcher {
+	w := &PolicyWatcher{Client: client, Logger: logger, changed: make(chan struct{}, 1)}
+	w.informer = dynamicinformer.NewFilteredDynamicInformer(client, NodeLabelPolicyResource, metav1.NamespaceAll,
+		resyncPeriod, cache.Indexers{}, nil).Informer()
+	_, _ = w.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{ // #nosec G104: the registration is never removed.
+		AddFunc: func(interface{}) { w.refresh() },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// Status updates leave the generation unchanged.
+			if oldObj.(*unstructured.Unstructured).GetGeneration() != newObj.(*unstructured.Unstructured).GetGeneration() {
+				w.refresh()
+			}
+		},
+		DeleteFunc: func(interface{}) { w.refresh() },
+	})
+	return w
+}
+
+// Start starts the informer in the background.
+func (w *PolicyWatcher) Start(ctx context.Context) {
+	go w.informer.RunWithContext(ctx)
+}
+
+// HasSynced returns true once the informer has listed all the policies.
+func (w *PolicyWatcher) HasSynced() bool {
+	return w.informer.HasSynced()
+}
+
+// PolicySet returns the current policies, or nil until they are synced so that the labels written from
+// policies are not mistaken for stale ones.
+func (w *PolicyWatcher) PolicySet() *PolicySet {
+	if !w.informer.HasSynced() {
+		return nil
+	}
+	return w.policies.Load()
+}
+
+// refresh compiles the policies of the informer store.
+func (w *PolicyWatcher) refresh() {
+	var policies []*NodeLabelPolicy
+	for _, obj := range w.informer.GetStore().List() {
+		policy := &NodeLabelPolicy{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.(*unstructured.Unstructured).Object, policy); err != nil {
+			w.Logger.Warn("Ignoring undecodable node label policy", zap.String("policy", obj.(*unstructured.Unstructured).GetName()), zap.Error(err))
+			continue
+		}
+		policies = append(policies, policy)
+	}
+	w.policies.Store(NewPolicySet(policies))
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+	if w.OnChange != nil && w.informer.HasSynced() {
+		w.OnChange()
+	}
+}
+
+// Run updates the status of the policies when they change and every status interval, until the context is done.
+// The informer must be started.
+func (w *PolicyWatcher) Run(ctx context.Context) {
+	if !cache.WaitForCacheSync(ctx.Done(), w.informer.HasSynced) {
+		return
+	}
+	ticker := time.NewTicker(policyStatusInterval)
+	defer ticker.Stop()
+	for {
+		if err := w.UpdateStatus(ctx); err != nil {
+			w.Logger.Warn("Failed to update node label policy status", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.changed:
+		}
+	}
+}
+
+// UpdateStatus writes the status of the policies whose status changed.
+func (w *PolicyWatcher) UpdateStatus(ctx context.Context) error {
+	set := w.PolicySet()
+	if set == nil {
+		return nil
+	}
+	nodes := w.Nodes()
+	var failed int
+	for _, policy := range set.policies {
+		if err := w.updatePolicyStatus(ctx, set, policy, nodes); err != nil {
+			w.Logger.Warn("Failed to update node label policy status", zap.String("policy", policy.policy.Name), zap.Error(err))
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to update the status of %d node label policies", failed)
+	}
+	return nil
+}
+
+// updatePolicyStatus writes the status of the policy if it changed. The current status is read from the
+// informer store, as status updates don't recompile the policies.
+func (w *PolicyWatcher) updatePolicyStatus(ctx context.Context, set *PolicySet, policy *compiledPolicy, nodes []*v1.Node) error {
+	obj, exists, err := w.informer.GetStore().GetByKey(policy.policy.Name)
+	if err != nil || !exists {
+		return err
+	}
+	current := &NodeLabelPolicy{}
+	if err = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.(*unstructured.Unstructured).Object, current); err != nil {
+		return err
+	}
+	status := set.policyStatus(policy, current.Status, nodes)
+	if reflect.DeepEqual(status, current.Status) {
+		return nil
+	}
+	value, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		return err
+	}
+	updated := obj.(*unstructured.Unstructured).DeepCopy()
+	updated.Object["status"] = value
+	_, err = w.Client.Resource(NodeLabelPolicyResource).UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	return err
+}
+
+// evaluatePolicies evaluates the node label policies for the node, or returns nil if policies are disabled or
+// not synced yet.
+func (c *VpcNodeLabelUpdater) evaluatePolicies(node *v1.Node, nodeinfo *NodeInfo) *PolicyResult {
+	if c.Policies == nil {
+		return nil
+	}
+	set := c.Policies()
+	if set == nil {
+		return nil
+	}
+	result, errs := set.Evaluate(node, nodeinfo)
+	for _, err := range errs {
+		c.Logger.Warn("Failed to render node label policy", zap.String("workerNodeName", node.Name), zap.Error(err))
+	}
+	return result
+}
+
+// expectedLabels returns the labels of the label schema and of the node label policies to set for the node
+// details, the label keys to remove from the node, the retired legacy labels and the labels no policy sets
+// anymore, and the result of the policies.
+func (c *VpcNodeLabelUpdater) expectedLabels(node *v1.Node, nodeinfo *NodeInfo) (map[string]string, []string, *PolicyResult) {
+	expected, retired := c.LabelSchemaOptions.desiredLabels(nodeinfo)
+	policies := c.evaluatePolicies(node, nodeinfo)
+	if policies == nil {
+		return expected, retired, nil
+	}
+	// Policies can't set the reserved keys of the label schema.
+	for key, value := range policies.Labels {
+		expected[key] = value
+	}
+	for _, key := range getPolicyKeys(node).Labels {
+		if _, ok := policies.Labels[key]; !ok {
+			retired = append(retired, key)
+		}
+	}
+	sort.Strings(retired)
+	return expected, retired, policies
+}
+
+// applyPolicyAnnotations sets the annotations of the policies on the updater's node, removes those no policy
+// sets anymore, and records the keys written from policies: the annotations and the managed labels the
+// policies set. Annotations not written from policies yet are subject to the conflict policies, and their
+// previous value is restored once no policy sets them anymore. It returns true if the annotations of the node
+// changed.
+func (c *VpcNodeLabelUpdater) applyPolicyAnnotations(managed ManagedLabels, policies *PolicyResult) (bool, error) {
+	if policies == nil {
+		return false, nil
+	}
+	before := make(map[string]string, len(c.Node.Annotations))
+	for key, value := range c.Node.Annotations {
+		before[key] = value
+	}
+	if c.Node.Annotations == nil {
+		c.Node.Annotations = map[string]string{}
+	}
+	current := getPolicyKeys(c.Node)
+	owned := make(map[string]bool, len(current.Annotations))
+	for _, key := range current.Annotations {
+		owned[key] = true
+		if _, ok := policies.Annotations[key]; ok {
+			continue
+		}
+		if previous, ok := current.PreviousAnnotations[key]; ok {
+			c.Node.Annotations[key] = previous
+		} else {
+			delete(c.Node.Annotations, key)
+		}
+	}
+	var keys policyKeys
+	for key, value := range policies.Annotations {
+		previous, ok := current.PreviousAnnotations[key]
+		if !owned[key] {
+			if previous, ok = c.Node.Annotations[key]; ok && previous != value {
+				apply, err := c.resolveConflict("Annotation", key, previous, value)
+				if err != nil {
+					return false, err
+				}
+				if !apply {
+					continue
+				}
+			}
+		}
+		if ok {
+			if keys.PreviousAnnotations == nil {
+				keys.PreviousAnnotations = map[string]string{}
+			}
+			keys.PreviousAnnotations[key] = previous
+		}
+		c.Node.Annotations[key] = value
+		keys.Annotations = append(keys.Annotations, key)
+	}
+	for key := range policies.Labels {
+		if _, ok := managed[key]; ok {
+			keys.Labels = append(keys.Labels, key)
+		}
+	}
+	sort.Strings(keys.Labels)
+	sort.Strings(keys.Annotations)
+	keys.setOn(c.Node)
+	return !reflect.DeepEqual(before, c.Node.Annotations), nil
+}
+
+// ListPolicies lists the node label policies once, e.g. for one-off commands which don't watch them.
+func ListPolicies(ctx context.Context, client dynamic.Interface) (*PolicySet, error) {
+	list, err := client.Resource(NodeLabelPolicyResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	policies := make([]*NodeLabelPolicy, 0, len(list.Items))
+	for i := range list.Items {
+		policy := &NodeLabelPolicy{}
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(list.Items[i].Object, policy); err != nil {
+			return nil, fmt.Errorf("failed to decode node label policy %s: %v", list.Items[i].GetName(), err)
+		}
+		policies = append(policies, policy)
+	}
+	return NewPolicySet(policies), nil
+}
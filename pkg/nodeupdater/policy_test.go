@@ -0,0 +1,279 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func testPolicy(name string, priority int32, selector map[string]string, labels, annotations map[string]string) *NodeLabelPolicy {
+	policy := &NodeLabelPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 1},
+		Spec:       NodeLabelPolicySpec{Priority: priority, Labels: labels, Annotations: annotations},
+	}
+	if selector != nil {
+		policy.Spec.NodeSelector = &metav1.LabelSelector{MatchLabels: selector}
+	}
+	return policy
+}
+
+func TestCompilePolicy(t *testing.T) {
+	testCases := []struct {
+		name   string
+		policy *NodeLabelPolicy
+		expErr bool
+	}{
+		{name: "Valid policy", policy: testPolicy("valid", 0, map[string]string{"pool": "a"}, map[string]string{"team": "{{ .Zone | upper }}"}, nil)},
+		{name: "Invalid selector", policy: testPolicy("selector", 0, map[string]string{"pool": "a b"}, nil, nil), expErr: true},
+		{name: "Reserved label", policy: testPolicy("reserved", 0, nil, map[string]string{topologyZoneLabelKey: "x"}, nil), expErr: true},
+		{name: "Reserved annotation", policy: testPolicy("reserved", 0, nil, nil, map[string]string{ManagedLabelsAnnotationKey: "x"}), expErr: true},
+		{name: "Kubernetes label", policy: testPolicy("kubernetes", 0, nil, map[string]string{"kubernetes.io/hostname": "x"}, nil), expErr: true},
+		{name: "Kubernetes subdomain label", policy: testPolicy("kubernetes", 0, nil, map[string]string{"node-role.kubernetes.io/worker": "x"}, nil), expErr: true},
+		{name: "Kubernetes annotation", policy: testPolicy("kubernetes", 0, nil, nil, map[string]string{"node.k8s.io/owner": "x"}), expErr: true},
+		{name: "Domain ending like a Kubernetes domain", policy: testPolicy("domain", 0, nil, map[string]string{"notkubernetes.io/team": "x"}, nil)},
+		{name: "Invalid key", policy: testPolicy("key", 0, nil, map[string]string{"a b": "x"}, nil), expErr: true},
+		{name: "Invalid template", policy: testPolicy("template", 0, nil, map[string]string{"team": "{{ .Zone"}, nil), expErr: true},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		compiled := compilePolicy(tc.policy)
+		assert.Equal(t, tc.expErr, compiled.err != nil)
+		status := NewPolicySet(nil).policyStatus(compiled, NodeLabelPolicyStatus{}, nil)
+		assert.Equal(t, tc.expErr, meta.IsStatusConditionFalse(status.Conditions, PolicyConditionAccepted))
+	}
+}
+
+func TestPolicySetEvaluate(t *testing.T) {
+	set := NewPolicySet([]*NodeLabelPolicy{
+		testPolicy("b-low", 0, nil, map[string]string{"team": "platform", "profile": "{{ .Instance.Profile.Name }}", "zone": "{{ .Zone }}"},
+			map[string]string{"owner": "platform"}),
+		testPolicy("a-high", 10, map[string]string{"pool": "gpu"}, map[string]string{"team": "ml", "profile": "{{ .Instance.Vcpu.Count }}"},
+			map[string]string{"owner": "ml@example.com"}),
+		testPolicy("c-tie", 10, nil, map[string]string{"team": "other", "node": "{{ replace \"-\" \".\" .NodeName }}"}, nil),
+		testPolicy("invalid", 100, nil, map[string]string{"a b": "x"}, nil),
+	})
+	nodeinfo := &NodeInfo{InstanceID: "instance-1", Region: "us-south", Zone: "us-south-1",
+		Instance: &Instance{ID: "instance-1", Profile: &Profile{Name: "bx2-4x16"}}}
+
+	testCases := []struct {
+		name           string
+		node           *v1.Node
+		expLabels      map[string]string
+		expAnnotations map[string]string
+		expErrs        int
+	}{
+		{
+			name:           "Node of the gpu pool",
+			node:           testNode("worker-1", map[string]string{"pool": "gpu"}),
+			expLabels:      map[string]string{"team": "ml", "profile": "bx2-4x16", "zone": "us-south-1", "node": "worker.1"},
+			expAnnotations: map[string]string{"owner": "ml@example.com"},
+			// The vCPU count of the instance is unknown, the profile is left to the lower priority policy.
+			expErrs: 1,
+		},
+		{
+			name:           "Node of another pool",
+			node:           testNode("worker-1", map[string]string{"pool": "default"}),
+			expLabels:      map[string]string{"team": "other", "profile": "bx2-4x16", "zone": "us-south-1", "node": "worker.1"},
+			expAnnotations: map[string]string{"owner": "platform"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		result, errs := set.Evaluate(tc.node, nodeinfo)
+		assert.Equal(t, tc.expLabels, result.Labels)
+		assert.Equal(t, tc.expAnnotations, result.Annotations)
+		assert.Len(t, errs, tc.expErrs)
+	}
+
+	// Labels written from policies don't select nodes.
+	node := testNode("worker-1", map[string]string{"pool": "gpu"})
+	policyKeys{Labels: []string{"pool"}}.setOn(node)
+	result, _ := set.Evaluate(node, nodeinfo)
+	assert.Equal(t, "other", result.Labels["team"])
+}
+
+func TestPolicySetEvaluateCachedInstance(t *testing.T) {
+	set := NewPolicySet([]*NodeLabelPolicy{
+		testPolicy("instance", 0, nil, map[string]string{"image": "{{ .Instance.Image.Name }}", "vcpu": "{{ .Instance.Vcpu.Count }}"},
+			map[string]string{"crn": "{{ .Instance.CRN }}", "href": "{{ .Instance.Href }}"}),
+	})
+	instance := &Instance{ID: "instance-1", Href: "https://us-south.iaas.cloud.ibm.com/v1/instances/instance-1", CRN: "crn:v1:instance-1",
+		Vcpu: &Vcpu{Count: 4}, Image: &Image{ID: "image-1", Name: "ibm-ubuntu-22-04", Href: "https://image"}}
+	node := testNode("worker-1", nil)
+
+	listed, errs := set.Evaluate(node, &NodeInfo{Instance: instance})
+	assert.Empty(t, errs)
+	cached, errs := set.Evaluate(node, &NodeInfo{Instance: compactInstance(instance)})
+	assert.Empty(t, errs)
+	assert.Equal(t, listed, cached)
+	assert.Equal(t, map[string]string{"image": "ibm-ubuntu-22-04", "vcpu": "4"}, listed.Labels)
+	// Fields not kept in the cache render empty from a fresh listing too.
+	assert.Equal(t, map[string]string{"crn": "crn:v1:instance-1", "href": ""}, listed.Annotations)
+}
+
+func TestVerifyNodeLabelWithPolicies(t *testing.T) {
+	labels, _ := LabelSchemaOptions{}.desiredLabels(&NodeInfo{InstanceID: "instance-1", Region: "us-south", Zone: "us-south-1"})
+	labels["pool"] = "gpu"
+	updater := initCachedNodeLabelUpdater(t, testNode("worker-1", labels))
+	policies := NewPolicySet([]*NodeLabelPolicy{
+		testPolicy("gpu", 0, map[string]string{"pool": "gpu"}, map[string]string{"team": "ml", "zone-name": "{{ .Instance.Zone.Name }}"},
+			map[string]string{"owner": "ml@example.com"}),
+	})
+	updater.DriftPolicy = DriftPolicyCorrect
+	updater.Policies = func() *PolicySet { return policies }
+
+	drifts, err := updater.VerifyNodeLabel(context.TODO(), "worker-1")
+	assert.Nil(t, err)
+	assert.Len(t, drifts, 2)
+	node, _ := updater.K8sClient.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+	assert.Equal(t, "ml", node.Labels["team"])
+	assert.Equal(t, "us-south-1", node.Labels["zone-name"])
+	assert.Equal(t, "ml@example.com", node.Annotations["owner"])
+	assert.Equal(t, policyKeys{Labels: []string{"team", "zone-name"}, Annotations: []string{"owner"}}, getPolicyKeys(node))
+	managed, _ := GetManagedLabels(node)
+	assert.Contains(t, managed, "team")
+
+	// Keys no policy sets anymore are removed.
+	policies = NewPolicySet([]*NodeLabelPolicy{
+		testPolicy("gpu", 0, map[string]string{"pool": "gpu"}, map[string]string{"team": "ml"}, nil),
+	})
+	updater.Node = node
+	drifts, err = updater.VerifyNodeLabel(context.TODO(), "worker-1")
+	assert.Nil(t, err)
+	assert.Equal(t, []LabelDrift{{Key: "zone-name", Actual: "us-south-1", Retired: true}}, drifts)
+	node, _ = updater.K8sClient.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+	assert.NotContains(t, node.Labels, "zone-name")
+	assert.NotContains(t, node.Annotations, "owner")
+	assert.Equal(t, policyKeys{Labels: []string{"team"}}, getPolicyKeys(node))
+
+	// Cleaning up removes the policy labels and annotations.
+	changed, err := CleanupNodeLabels(node, false)
+	assert.True(t, changed)
+	assert.Nil(t, err)
+	assert.NotContains(t, node.Labels, "team")
+	assert.NotContains(t, node.Annotations, PolicyKeysAnnotationKey)
+}
+
+func TestPolicyAnnotationConflict(t *testing.T) {
+	labels, _ := LabelSchemaOptions{}.desiredLabels(&NodeInfo{InstanceID: "instance-1", Region: "us-south", Zone: "us-south-1"})
+	testCases := []struct {
+		name          string
+		policy        ConflictPolicy
+		expErr        bool
+		expAnnotation string
+	}{
+		{name: "Overwrite", policy: ConflictPolicyOverwrite, expAnnotation: "ml@example.com"},
+		{name: "Keep", policy: ConflictPolicyKeep, expAnnotation: "someone@example.com"},
+		{name: "Fail", policy: ConflictPolicyFail, expErr: true, expAnnotation: "someone@example.com"},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		node := testNode("worker-1", labels)
+		node.Annotations = map[string]string{"owner": "someone@example.com"}
+		updater := initCachedNodeLabelUpdater(t, node)
+		policies := NewPolicySet([]*NodeLabelPolicy{testPolicy("owner", 0, nil, nil, map[string]string{"owner": "ml@example.com"})})
+		updater.DriftPolicy = DriftPolicyCorrect
+		updater.ConflictPolicies = LabelConflictPolicies{Default: tc.policy}
+		updater.Policies = func() *PolicySet { return policies }
+
+		_, err := updater.VerifyNodeLabel(context.TODO(), "worker-1")
+		assert.Equal(t, tc.expErr, err != nil)
+		node, _ = updater.K8sClient.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+		assert.Equal(t, tc.expAnnotation, node.Annotations["owner"])
+		if tc.policy != ConflictPolicyOverwrite {
+			assert.Empty(t, getPolicyKeys(node).Annotations)
+			continue
+		}
+		assert.Equal(t, map[string]string{"owner": "someone@example.com"}, getPolicyKeys(node).PreviousAnnotations)
+
+		// The annotation taken over gets its previous value back once no policy sets it.
+		policies = NewPolicySet(nil)
+		updater.Node = node
+		_, err = updater.VerifyNodeLabel(context.TODO(), "worker-1")
+		assert.Nil(t, err)
+		node, _ = updater.K8sClient.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+		assert.Equal(t, "someone@example.com", node.Annotations["owner"])
+		assert.NotContains(t, node.Annotations, PolicyKeysAnnotationKey)
+	}
+}
+
+func TestPolicyWatcher(t *testing.T) {
+	scheme := runtime.NewScheme()
+	var objects []runtime.Object
+	for _, policy := range []*NodeLabelPolicy{
+		testPolicy("high", 10, map[string]string{"pool": "gpu"}, map[string]string{"team": "ml"}, nil),
+		testPolicy("low", 0, nil, map[string]string{"team": "platform"}, nil),
+		testPolicy("invalid", 0, nil, map[string]string{topologyZoneLabelKey: "x"}, nil),
+	} {
+		policy.APIVersion = NodeLabelPolicyResource.GroupVersion().String()
+		policy.Kind = "NodeLabelPolicy"
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(policy)
+		assert.Nil(t, err)
+		objects = append(objects, &unstructured.Unstructured{Object: obj})
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme,
+		map[schema.GroupVersionResource]string{NodeLabelPolicyResource: "NodeLabelPolicyList"}, objects...)
+
+	watcher := NewPolicyWatcher(client, zap.NewNop(), 0)
+	watcher.Nodes = func() []*v1.Node {
+		return []*v1.Node{testNode("worker-1", map[string]string{"pool": "gpu"}), testNode("worker-2", nil)}
+	}
+	assert.Nil(t, watcher.PolicySet())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watcher.Start(ctx)
+	assert.True(t, cache.WaitForCacheSync(ctx.Done(), watcher.HasSynced))
+	assert.Len(t, watcher.PolicySet().policies, 3)
+
+	assert.Nil(t, watcher.UpdateStatus(ctx))
+	getStatus := func(name string) NodeLabelPolicyStatus {
+		obj, err := client.Resource(NodeLabelPolicyResource).Get(ctx, name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		policy := &NodeLabelPolicy{}
+		assert.Nil(t, runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, policy))
+		return policy.Status
+	}
+	high, low, invalid := getStatus("high"), getStatus("low"), getStatus("invalid")
+	assert.Equal(t, int32(1), high.MatchedNodes)
+	assert.True(t, meta.IsStatusConditionTrue(high.Conditions, PolicyConditionAccepted))
+	assert.True(t, meta.IsStatusConditionFalse(high.Conditions, PolicyConditionOverridden))
+	assert.Equal(t, int32(2), low.MatchedNodes)
+	assert.True(t, meta.IsStatusConditionTrue(low.Conditions, PolicyConditionOverridden))
+	assert.Contains(t, meta.FindStatusCondition(low.Conditions, PolicyConditionOverridden).Message, "label team")
+	assert.True(t, meta.IsStatusConditionFalse(invalid.Conditions, PolicyConditionAccepted))
+
+	// Unchanged status is not written again once the informer saw it.
+	assert.Eventually(t, func() bool {
+		client.ClearActions()
+		assert.Nil(t, watcher.UpdateStatus(ctx))
+		return len(client.Actions()) == 0
+	}, 5*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,215 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
+	"go.uber.org/zap"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PreflightStatus ...
+type PreflightStatus string
+
+const (
+	// PreflightPass ...
+	PreflightPass PreflightStatus = "pass"
+	// PreflightFail ...
+	PreflightFail PreflightStatus = "fail"
+	// PreflightSkip is the status of a check which depends on a failed check.
+	PreflightSkip PreflightStatus = "skip"
+)
+
+// PreflightResult is the result of a single preflight check.
+type PreflightResult struct {
+	Name    string          `json:"name"`
+	Status  PreflightStatus `json:"status"`
+	Message string          `json:"message,omitempty"`
+}
+
+// PreflightReport lists the results of the preflight checks in the order they ran.
+type PreflightReport struct {
+	Passed bool              `json:"passed"`
+	Checks []PreflightResult `json:"checks"`
+}
+
+// add records the result of a check, which passed if err is nil.
+func (r *PreflightReport) add(name string, message string, err error) bool {
+	if err != nil {
+		r.Passed = false
+		r.Checks = append(r.Checks, PreflightResult{Name: name, Status: PreflightFail, Message: err.Error()})
+		return false
+	}
+	r.Checks = append(r.Checks, PreflightResult{Name: name, Status: PreflightPass, Message: message})
+	return true
+}
+
+// skip records a check which was not run because the check it depends on failed.
+func (r *PreflightReport) skip(name string, dependency string) {
+	r.Checks = append(r.Checks, PreflightResult{Name: name, Status: PreflightSkip, Message: fmt.Sprintf("requires %s", dependency)})
+}
+
+// WriteText writes the report as one line per check followed by a summary.
+func (r PreflightReport) WriteText(w io.Writer) error {
+	failed := 0
+	for _, check := range r.Checks {
+		line := fmt.Sprintf("[%s] %s", strings.ToUpper(string(check.Status)), check.Name)
+		if check.Message != "" {
+			line += ": " + check.Message
+		}
+		if check.Status == PreflightFail {
+			failed++
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	summary := "Preflight checks passed"
+	if !r.Passed {
+		summary = fmt.Sprintf("Preflight checks failed: %d of %d checks failed", failed, len(r.Checks))
+	}
+	_, err := fmt.Fprintln(w, summary)
+	return err
+}
+
+const (
+	preflightSecretConfig = "secret configuration"
+	preflightIAMToken     = "IAM token"
+	preflightRIAAS        = "RIAAS endpoint"
+	preflightNodeInstance = "node instance"
+)
+
+// RunPreflight checks, one by one, everything labeling the node depends on: the RBAC permissions of the
+// updater, the secret configuration, the IAM token exchange, the reachability of the RIAAS endpoint and
+// the resolution of the node to exactly one VPC instance. The secret configuration is read from source.
+func RunPreflight(ctx context.Context, k8sClient *k8s_utils.KubernetesClient, source SecretConfigSource, nodeName string, logger *zap.Logger) PreflightReport {
+	report := PreflightReport{Passed: true}
+
+	permissions := []authorizationv1.ResourceAttributes{
+		{Verb: "get", Resource: "nodes", Name: nodeName},
+		{Verb: "update", Resource: "nodes", Name: nodeName},
+		{Verb: "get", Resource: "secrets", Namespace: k8sClient.Namespace},
+	}
+	for _, permission := range permissions {
+		name := fmt.Sprintf("RBAC %s %s", permission.Verb, permission.Resource)
+		report.add(name, "", checkAccess(ctx, k8sClient.Clientset, permission))
+	}
+
+	secretConfig, err := source()
+	message := ""
+	if err == nil {
+		message = fmt.Sprintf("RIAAS endpoint %s", secretConfig.RiaasEndpointURL.Host)
+	}
+	if !report.add(preflightSecretConfig, message, err) {
+		report.skip(preflightIAMToken, preflightSecretConfig)
+		report.skip(preflightRIAAS, preflightSecretConfig)
+		report.skip(preflightNodeInstance, preflightSecretConfig)
+		return report
+	}
+
+	tokenOK := report.add(preflightIAMToken, "", secretConfig.checkToken(ctx))
+	report.add(preflightRIAAS, "", secretConfig.checkRIAASReachable(ctx))
+	if !tokenOK {
+		report.skip(preflightNodeInstance, preflightIAMToken)
+		return report
+	}
+
+	updater := &VpcNodeLabelUpdater{Logger: logger, StorageSecretConfig: secretConfig}
+	instance, err := updater.resolveNodeInstance(ctx, nodeName)
+	message = ""
+	if err == nil {
+		message = fmt.Sprintf("instance %s", instance.ID)
+	}
+	report.add(preflightNodeInstance, message, err)
+	return report
+}
+
+// checkAccess fails when the updater is not allowed to act on the resource, according to a SelfSubjectAccessReview.
+func checkAccess(ctx context.Context, client kubernetes.Interface, attributes authorizationv1.ResourceAttributes) error {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attributes},
+	}
+	result, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to review access: %v", err)
+	}
+	if !result.Status.Allowed {
+		reason := result.Status.Reason
+		if reason == "" {
+			reason = "no RBAC rule allows it"
+		}
+		return fmt.Errorf("not allowed to %s %s: %s", attributes.Verb, attributes.Resource, reason)
+	}
+	return nil
+}
+
+// checkToken fails when the IAM token exchange fails.
+func (s *StorageSecretConfig) checkToken(ctx context.Context) error {
+	token, err := s.AccessToken(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get IAM token: %v", err)
+	}
+	if token == "" {
+		return errors.New("no IAM token")
+	}
+	return nil
+}
+
+// resolveNodeInstance returns the only instance whose name, or primary IPv4 address if the node name is an IP,
+// matches the node name, and fails when there is none or more than one.
+func (c *VpcNodeLabelUpdater) resolveNodeInstance(ctx context.Context, nodeName string) (*Instance, error) {
+	if nodeName == "" {
+		return nil, errors.New("no node name, NODE_NAME is not set")
+	}
+	byIP := net.ParseIP(nodeName) != nil
+	params := url.Values{"limit": {instanceListPageLimit}}
+	if !byIP {
+		params.Set("name", nodeName)
+	}
+	instances, err := c.GetInstancesFromVPC(ctx, c.instanceListURL(params))
+	if err != nil {
+		return nil, err
+	}
+	var matches []*Instance
+	for _, instance := range instances {
+		if (byIP && primaryIPv4Address(instance) == nodeName) || (!byIP && instance.Name == nodeName) {
+			matches = append(matches, instance)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("node %s does not match any instance", nodeName)
+	case 1:
+		return matches[0], nil
+	}
+	ids := make([]string, 0, len(matches))
+	for _, instance := range matches {
+		ids = append(ids, instance.ID)
+	}
+	return nil, fmt.Errorf("node %s matches %d instances: %s", nodeName, len(matches), strings.Join(ids, ", "))
+}
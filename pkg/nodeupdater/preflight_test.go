@@ -0,0 +1,115 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
+	"github.com/stretchr/testify/assert"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// allowResources answers SelfSubjectAccessReviews, allowing access to the given resources only.
+func allowResources(client *fake.Clientset, resources ...string) {
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		for _, resource := range resources {
+			if review.Spec.ResourceAttributes.Resource == resource {
+				review.Status.Allowed = true
+			}
+		}
+		return true, review, nil
+	})
+}
+
+func TestRunPreflight(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	// No secret configuration, the checks depending on it are skipped.
+	k8sClient, _ := k8s_utils.FakeGetk8sClientSet()
+	allowResources(k8sClient.Clientset.(*fake.Clientset), "nodes")
+	report := RunPreflight(context.TODO(), &k8sClient, SecretProviderSource(&k8sClient, logger), "worker-1", logger)
+
+	assert.False(t, report.Passed)
+	statuses := map[string]PreflightStatus{}
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	assert.Equal(t, map[string]PreflightStatus{
+		"RBAC get nodes":      PreflightPass,
+		"RBAC update nodes":   PreflightPass,
+		"RBAC get secrets":    PreflightFail,
+		preflightSecretConfig: PreflightFail,
+		preflightIAMToken:     PreflightSkip,
+		preflightRIAAS:        PreflightSkip,
+		preflightNodeInstance: PreflightSkip,
+	}, statuses)
+
+	var output strings.Builder
+	assert.Nil(t, report.WriteText(&output))
+	assert.Contains(t, output.String(), "[PASS] RBAC get nodes\n")
+	assert.Contains(t, output.String(), "[FAIL] RBAC get secrets: not allowed to get secrets")
+	assert.Contains(t, output.String(), "[SKIP] IAM token: requires secret configuration\n")
+	assert.Contains(t, output.String(), "Preflight checks failed: 2 of 7 checks failed\n")
+}
+
+func TestResolveNodeInstance(t *testing.T) {
+	instances := testInstances()
+	instances = append(instances, &Instance{ID: "instance-4", Name: "worker-4", Zone: &Zone{Name: "us-south-1"}, PrimaryNetworkInterface: &NetworkInterface{PrimaryIpv4Address: "10.0.0.3"}})
+	requests := 0
+	server := newFakeRiaasServer(t, instances, &requests)
+
+	testCases := []struct {
+		name       string
+		nodeName   string
+		expectedID string
+		expErr     string
+	}{
+		{name: "By name", nodeName: "worker-2", expectedID: "instance-2"},
+		{name: "By IP", nodeName: "10.0.0.1", expectedID: "instance-1"},
+		{name: "No instance", nodeName: "worker-9", expErr: "does not match any instance"},
+		{name: "Several instances", nodeName: "10.0.0.3", expErr: "matches 2 instances: instance-3, instance-4"},
+		{name: "No node name", nodeName: "", expErr: "NODE_NAME is not set"},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		updater := initNodeLabelUpdater(t)
+		updater.StorageSecretConfig.RiaasEndpointURL, _ = url.Parse(server.URL + "/v1/instances?generation=2")
+		instance, err := updater.resolveNodeInstance(context.TODO(), tc.nodeName)
+		if tc.expErr != "" {
+			assert.NotNil(t, err)
+			if err != nil {
+				assert.Contains(t, err.Error(), tc.expErr)
+			}
+			continue
+		}
+		assert.Nil(t, err)
+		if assert.NotNil(t, instance) {
+			assert.Equal(t, tc.expectedID, instance.ID)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const redacted = "***"
+
+var (
+	// crnAccountPattern matches the account scope of a CRN, e.g. crn:v1:bluemix:public:is:us-south-1:a/<account id>::instance:<id>.
+	crnAccountPattern = regexp.MustCompile(`(crn:v1:[^:\s"]*:[^:\s"]*:[^:\s"]*:[^:\s"]*:)a/[0-9a-zA-Z]+`)
+	ipv4Pattern       = regexp.MustCompile(`\b(\d{1,3})\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`)
+	bearerPattern     = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[^\s"]+`)
+	jwtPattern        = regexp.MustCompile(`\beyJ[\w-]+\.[\w-]+\.[\w-]+`)
+	urlPattern        = regexp.MustCompile(`https?://[^\s"']+`)
+
+	// sensitiveKeys are the field and JSON keys whose values are always removed, credentials and
+	// resource group IDs.
+	sensitiveKeys = map[string]bool{
+		"token": true, "access_token": true, "accesstoken": true, "iamaccesstoken": true, "refresh_token": true,
+		"apikey": true, "api_key": true, "g2_api_key": true, "authorization": true, "password": true, "secret": true,
+		"resource_group": true, "resource_group_id": true, "g2_resource_group_id": true,
+	}
+)
+
+// RedactionPolicy controls the redaction of sensitive data from logs.
+type RedactionPolicy struct {
+	// FullDetail leaves debug entries unredacted, for troubleshooting with the debug log level.
+	// Entries at info level and above are always redacted.
+	FullDetail bool
+}
+
+// RedactString masks the tokens, the account IDs of CRNs and the IP addresses in the string, and removes
+// the credentials and query of URLs.
+func RedactString(s string) string {
+	s = urlPattern.ReplaceAllStringFunc(s, redactURL)
+	s = bearerPattern.ReplaceAllString(s, "$1 "+redacted)
+	s = jwtPattern.ReplaceAllString(s, redacted)
+	s = crnAccountPattern.ReplaceAllString(s, "${1}a/"+redacted)
+	return ipv4Pattern.ReplaceAllString(s, "$1.*.*.*")
+}
+
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return redacted
+	}
+	u.User = nil
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			query[key] = []string{redacted}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// redactValue redacts the strings of a value decoded from JSON, and removes the values of sensitive keys.
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return RedactString(v)
+	case map[string]interface{}:
+		for key, item := range v {
+			if sensitiveKeys[strings.ToLower(key)] {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redactValue(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	}
+	return value
+}
+
+// redactedObject logs the redacted fields of an object, decoded by a map encoder, in key order.
+type redactedObject map[string]interface{}
+
+func (o redactedObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	keys := make([]string, 0, len(o))
+	for key := range o {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := enc.AddReflected(key, o[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redactMarshaler decodes the arrays and objects logged with zap.Strings, zap.Object, zap.Inline and the
+// like, and redacts them like the JSON form of reflected values.
+func redactMarshaler(field zapcore.Field) zapcore.Field {
+	enc := zapcore.NewMapObjectEncoder()
+	var err error
+	switch marshaler := field.Interface.(type) {
+	case zapcore.ArrayMarshaler:
+		err = enc.AddArray(field.Key, marshaler)
+	case zapcore.ObjectMarshaler:
+		if field.Type == zapcore.InlineMarshalerType {
+			err = marshaler.MarshalLogObject(enc)
+			if err == nil {
+				return zap.Inline(redactedObject(redactValue(enc.Fields).(map[string]interface{})))
+			}
+		} else {
+			err = enc.AddObject(field.Key, marshaler)
+		}
+	default:
+		return field
+	}
+	if err != nil {
+		return zap.String(field.Key, redacted)
+	}
+	value := redactValue(enc.Fields[field.Key])
+	if object, ok := value.(map[string]interface{}); ok {
+		return zap.Object(field.Key, redactedObject(object))
+	}
+	return zap.Reflect(field.Key, value)
+}
+
+// redactField returns the field with its sensitive data redacted.
+func redactField(field zapcore.Field) zapcore.Field {
+	if sensitiveKeys[strings.ToLower(field.Key)] {
+		return zap.String(field.Key, redacted)
+	}
+	switch field.Type {
+	case zapcore.StringType:
+		return zap.String(field.Key, RedactString(field.String))
+	case zapcore.ByteStringType:
+		if data, ok := field.Interface.([]byte); ok {
+			return zap.String(field.Key, RedactString(string(data)))
+		}
+	case zapcore.ArrayMarshalerType, zapcore.ObjectMarshalerType, zapcore.InlineMarshalerType:
+		return redactMarshaler(field)
+	case zapcore.ErrorType:
+		if err, ok := field.Interface.(error); ok && err != nil {
+			return zap.String(field.Key, RedactString(err.Error()))
+		}
+	case zapcore.StringerType:
+		if stringer, ok := field.Interface.(fmt.Stringer); ok && stringer != nil {
+			return zap.String(field.Key, RedactString(stringer.String()))
+		}
+	case zapcore.ReflectType:
+		// Structured values are redacted through their JSON form, which the encoder would produce anyway.
+		data, err := json.Marshal(field.Interface)
+		if err != nil {
+			return zap.String(field.Key, redacted)
+		}
+		var value interface{}
+		if err = json.Unmarshal(data, &value); err != nil {
+			return zap.String(field.Key, redacted)
+		}
+		return zap.Reflect(field.Key, redactValue(value))
+	}
+	return field
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	result := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		result[i] = redactField(field)
+	}
+	return result
+}
+
+// redactingCore redacts the message and fields of the entries written by the wrapped core.
+type redactingCore struct {
+	// Core holds the unredacted fields added with With, for unredacted debug entries.
+	zapcore.Core
+	policy RedactionPolicy
+	// redactedCore is the wrapped core with the redacted fields added with With.
+	redactedCore zapcore.Core
+}
+
+// NewRedactingCore wraps the core so that sensitive data is redacted from the logged entries,
+// according to the policy.
+func NewRedactingCore(core zapcore.Core, policy RedactionPolicy) zapcore.Core {
+	return &redactingCore{Core: core, policy: policy, redactedCore: core}
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{
+		Core:         c.Core.With(fields),
+		policy:       c.policy,
+		redactedCore: c.redactedCore.With(redactFields(fields)),
+	}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if c.policy.FullDetail && entry.Level <= zapcore.DebugLevel {
+		return c.Core.Write(entry, fields)
+	}
+	entry.Message = RedactString(entry.Message)
+	return c.redactedCore.Write(entry, redactFields(fields))
+}
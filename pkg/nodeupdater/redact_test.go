@@ -0,0 +1,113 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedactString(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "crn", input: "crn:v1:bluemix:public:is:us-south-1:a/0123456789abcdef0123456789abcdef::instance:0717-abc",
+			expected: "crn:v1:bluemix:public:is:us-south-1:a/***::instance:0717-abc"},
+		{name: "ip", input: "worker 10.240.0.17 not found", expected: "worker 10.*.*.* not found"},
+		{name: "bearer token", input: "Authorization: Bearer abc.def", expected: "Authorization: Bearer ***"},
+		{name: "jwt", input: "token eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.c2ln", expected: "token ***"},
+		{name: "url", input: `Get "https://user:pw@us-south.iaas.cloud.ibm.com/v1/instances?name=worker-1&version=2020-01-01": EOF`,
+			expected: `Get "https://us-south.iaas.cloud.ibm.com/v1/instances?name=%2A%2A%2A&version=%2A%2A%2A": EOF`},
+		{name: "nothing sensitive", input: "us-south-1", expected: "us-south-1"},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		assert.Equal(t, tc.expected, RedactString(tc.input))
+	}
+}
+
+func TestRedactingCore(t *testing.T) {
+	instance := &Instance{ID: "instance-1", Name: "worker-1", CRN: "crn:v1:bluemix:public:is:us-south-1:a/0123456789abcdef::instance:instance-1",
+		PrimaryNetworkInterface: &NetworkInterface{PrimaryIpv4Address: "10.0.0.1"}, ResourceGroup: &ResourceGroup{ID: "0123456789abcdef"}}
+	testCases := []struct {
+		name       string
+		policy     RedactionPolicy
+		level      zapcore.Level
+		expRedacts bool
+	}{
+		{name: "info", level: zapcore.InfoLevel, expRedacts: true},
+		{name: "debug", level: zapcore.DebugLevel, expRedacts: true},
+		{name: "debug with full detail", policy: RedactionPolicy{FullDetail: true}, level: zapcore.DebugLevel},
+		{name: "info with full detail", policy: RedactionPolicy{FullDetail: true}, level: zapcore.InfoLevel, expRedacts: true},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		core, logs := observer.New(zapcore.DebugLevel)
+		logger := zap.New(NewRedactingCore(core, tc.policy)).With(zap.String("workerNodeName", "10.0.0.1"))
+		if ce := logger.Check(tc.level, "found instance 10.0.0.1"); ce != nil {
+			ce.Write(zap.Reflect("instance", instance), zap.Error(errors.New("dial 10.0.0.2 failed")), zap.String("token", "secret"))
+		}
+
+		entry := logs.All()[0]
+		fields := entry.ContextMap()
+		if !tc.expRedacts {
+			assert.Equal(t, "found instance 10.0.0.1", entry.Message)
+			assert.Equal(t, "10.0.0.1", fields["workerNodeName"])
+			assert.Equal(t, "secret", fields["token"])
+			continue
+		}
+		assert.Equal(t, "found instance 10.*.*.*", entry.Message)
+		assert.Equal(t, "10.*.*.*", fields["workerNodeName"])
+		assert.Equal(t, "dial 10.*.*.* failed", fields["error"])
+		assert.Equal(t, "***", fields["token"])
+		redactedInstance := fields["instance"].(map[string]interface{})
+		assert.Equal(t, "crn:v1:bluemix:public:is:us-south-1:a/***::instance:instance-1", redactedInstance["crn"])
+		assert.Equal(t, "***", redactedInstance["resource_group"])
+		assert.Equal(t, "10.*.*.*", redactedInstance["primary_network_interface"].(map[string]interface{})["primary_ipv4_address"])
+	}
+}
+
+func TestRedactingCoreMarshalers(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(NewRedactingCore(core, RedactionPolicy{}))
+	object := zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("apikey", "secret")
+		enc.AddString("ip", "10.0.0.1")
+		return enc.AddArray("ips", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+			arr.AppendString("10.0.0.2")
+			return nil
+		}))
+	})
+	logger.Info("found instances", zap.Strings("workers", []string{"10.0.0.1", "worker-2"}), zap.Object("instance", object),
+		zap.Inline(object), zap.ByteString("body", []byte("Bearer abc")))
+
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, []interface{}{"10.*.*.*", "worker-2"}, fields["workers"])
+	assert.Equal(t, map[string]interface{}{"apikey": "***", "ip": "10.*.*.*", "ips": []interface{}{"10.*.*.*"}}, fields["instance"])
+	assert.Equal(t, "***", fields["apikey"])
+	assert.Equal(t, "10.*.*.*", fields["ip"])
+	assert.Equal(t, []interface{}{"10.*.*.*"}, fields["ips"])
+	assert.Equal(t, "Bearer ***", fields["body"])
+}
@@ -0,0 +1,169 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ClusterInfoConfigMap is the configmap the secret provider reads the cluster details from.
+const ClusterInfoConfigMap = "cluster-info"
+
+// SecretConfigReloader keeps the secret configuration of a long running updater up to date: when the
+// storage-secret-store secret or the cluster-info configmap change, the configuration is read again from
+// its source and replaces the current one at once, with a new token source so cached tokens are dropped.
+// A configuration which can't be read or issue a token is reported, and the last good one stays in use.
+type SecretConfigReloader struct {
+	K8sClient kubernetes.Interface
+	Namespace string
+	Source    SecretConfigSource
+	Logger    *zap.Logger
+
+	current atomic.Pointer[StorageSecretConfig]
+	changed chan struct{}
+}
+
+// NewSecretConfigReloader returns a reloader of the configuration of source, starting with current.
+func NewSecretConfigReloader(k8sClient kubernetes.Interface, namespace string, source SecretConfigSource, current *StorageSecretConfig, logger *zap.Logger) *SecretConfigReloader {
+	r := &SecretConfigReloader{
+		K8sClient: k8sClient,
+		Namespace: namespace,
+		Source:    source,
+		Logger:    logger,
+		changed:   make(chan struct{}, 1),
+	}
+	r.current.Store(current)
+	return r
+}
+
+// Current returns the last good secret configuration.
+func (r *SecretConfigReloader) Current() *StorageSecretConfig {
+	return r.current.Load()
+}
+
+// Reload reads the secret configuration and fetches a token with it. On success it replaces the current
+// configuration, on error the current one is kept.
+func (r *SecretConfigReloader) Reload(ctx context.Context) error {
+	config, err := ReadSecretConfigurationFrom(ctx, r.Source, r.Logger)
+	if err != nil {
+		secretConfigReloadsTotal.WithLabelValues("failure").Inc()
+		r.Logger.Error("Failed to reload secret configuration, keeping the current one", zap.Error(err))
+		return err
+	}
+	previous := r.current.Swap(config)
+	secretConfigReloadsTotal.WithLabelValues("success").Inc()
+	if previous == nil || previous.RiaasEndpointURL.String() != config.RiaasEndpointURL.String() {
+		r.Logger.Info("Reloaded secret configuration with new RIAAS endpoint", zap.String("riaasEndpoint", config.RiaasEndpointURL.Host))
+	} else {
+		r.Logger.Info("Reloaded secret configuration")
+	}
+	return nil
+}
+
+// Run watches the secret and the configmap and reloads the configuration when they change, until the context
+// is done. Objects listed when the watch starts don't trigger a reload.
+func (r *SecretConfigReloader) Run(ctx context.Context) {
+	secrets := r.newInformer(&v1.Secret{}, utils.STORAGE_SECRET_STORE_SECRET,
+		func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return r.K8sClient.CoreV1().Secrets(r.Namespace).List(ctx, options)
+		},
+		func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			return r.K8sClient.CoreV1().Secrets(r.Namespace).Watch(ctx, options)
+		})
+	configMaps := r.newInformer(&v1.ConfigMap{}, ClusterInfoConfigMap,
+		func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return r.K8sClient.CoreV1().ConfigMaps(r.Namespace).List(ctx, options)
+		},
+		func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			return r.K8sClient.CoreV1().ConfigMaps(r.Namespace).Watch(ctx, options)
+		})
+	go secrets.RunWithContext(ctx)
+	go configMaps.RunWithContext(ctx)
+	if !cache.WaitForCacheSync(ctx.Done(), secrets.HasSynced, configMaps.HasSynced) {
+		return
+	}
+	r.Logger.Info("Watching secret configuration", zap.String("namespace", r.Namespace))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.changed:
+			_ = r.Reload(ctx) // #nosec G104: errors are logged, the current configuration stays in use.
+		}
+	}
+}
+
+// newInformer returns an informer of the object of the given name, signaling its changes once synced.
+func (r *SecretConfigReloader) newInformer(obj runtime.Object, name string, list cache.ListWithContextFunc, watchFunc cache.WatchFuncWithContext) cache.SharedIndexInformer {
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+	informer := cache.NewSharedIndexInformer(cache.ToListWatcherWithWatchListSemantics(&cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = selector
+			return list(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = selector
+			return watchFunc(ctx, options)
+		},
+	}, r.K8sClient), obj, 0, cache.Indexers{})
+	// The field selector is not applied by every client, the name is checked again.
+	matches := func(obj interface{}) bool {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		object, ok := obj.(metav1.Object)
+		return ok && object.GetName() == name
+	}
+	_, _ = informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{ // #nosec G104: the registration is never removed.
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if !isInInitialList && matches(obj) {
+				r.signal()
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if matches(newObj) && oldObj.(metav1.Object).GetResourceVersion() != newObj.(metav1.Object).GetResourceVersion() {
+				r.signal()
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if matches(obj) {
+				r.signal()
+			}
+		},
+	})
+	return informer
+}
+
+// signal queues a reload, changes arriving before it runs are coalesced.
+func (r *SecretConfigReloader) signal() {
+	select {
+	case r.changed <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,156 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeSecretConfigSource returns configurations of the current endpoint and token, counting its calls.
+type fakeSecretConfigSource struct {
+	mutex    sync.Mutex
+	calls    int
+	endpoint string
+	token    string
+	err      error
+}
+
+func (f *fakeSecretConfigSource) set(endpoint, token string, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.endpoint, f.token, f.err = endpoint, token, err
+}
+
+func (f *fakeSecretConfigSource) source() (*StorageSecretConfig, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	token := f.token
+	return &StorageSecretConfig{
+		RiaasEndpointURL: &url.URL{Scheme: "https", Host: f.endpoint, Path: "/v1/instances"},
+		TokenSource: NewTokenSource(func() (string, uint64, error) {
+			if token == "" {
+				return "", 0, errors.New("invalid API key")
+			}
+			return token, 3600, nil
+		}),
+	}, nil
+}
+
+func (f *fakeSecretConfigSource) callCount() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.calls
+}
+
+func TestSecretConfigReloaderReload(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	source := &fakeSecretConfigSource{}
+	source.set("us-south.iaas.cloud.ibm.com", "token-1", nil)
+	initial, err := ReadSecretConfigurationFrom(context.TODO(), source.source, logger)
+	assert.Nil(t, err)
+	reloader := NewSecretConfigReloader(fake.NewSimpleClientset(), "kube-system", source.source, initial, logger)
+	updater := &VpcNodeLabelUpdater{StorageSecretConfig: initial, CurrentSecretConfig: reloader.Current}
+
+	testCases := []struct {
+		name        string
+		endpoint    string
+		token       string
+		err         error
+		expErr      bool
+		expEndpoint string
+		expToken    string
+	}{
+		{name: "Rotated API key", endpoint: "us-south.iaas.cloud.ibm.com", token: "token-2", expEndpoint: "us-south.iaas.cloud.ibm.com", expToken: "token-2"},
+		{name: "Changed endpoint", endpoint: "eu-de.iaas.cloud.ibm.com", token: "token-3", expEndpoint: "eu-de.iaas.cloud.ibm.com", expToken: "token-3"},
+		{name: "Invalid API key", endpoint: "us-east.iaas.cloud.ibm.com", expErr: true, expEndpoint: "eu-de.iaas.cloud.ibm.com", expToken: "token-3"},
+		{name: "Unreadable secret", err: errors.New("secret not found"), expErr: true, expEndpoint: "eu-de.iaas.cloud.ibm.com", expToken: "token-3"},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		source.set(tc.endpoint, tc.token, tc.err)
+		err := reloader.Reload(context.TODO())
+		assert.Equal(t, tc.expErr, err != nil)
+		assert.Equal(t, tc.expEndpoint, updater.SecretConfig().RiaasEndpointURL.Host)
+		token, err := updater.SecretConfig().AccessToken(context.TODO())
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expToken, token)
+		assert.Equal(t, tc.expEndpoint, updater.instanceListURL(nil).Host)
+	}
+}
+
+func TestSecretConfigReloaderRun(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: utils.STORAGE_SECRET_STORE_SECRET, Namespace: "kube-system", ResourceVersion: "1"}}
+	configMap := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: ClusterInfoConfigMap, Namespace: "kube-system", ResourceVersion: "1"}}
+	other := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "kube-system", ResourceVersion: "1"}}
+	client := fake.NewSimpleClientset(secret, configMap, other)
+	source := &fakeSecretConfigSource{}
+	source.set("us-south.iaas.cloud.ibm.com", "token-1", nil)
+	initial, _ := source.source()
+	reloader := NewSecretConfigReloader(client, "kube-system", source.source, initial, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reloader.Run(ctx)
+
+	// The watch is established once the listed objects are known, changes before are not seen.
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, 1, source.callCount())
+
+	source.set("eu-de.iaas.cloud.ibm.com", "token-2", nil)
+	secret.ResourceVersion = "2"
+	_, err := client.CoreV1().Secrets("kube-system").Update(ctx, secret, metav1.UpdateOptions{})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		return reloader.Current().RiaasEndpointURL.Host == "eu-de.iaas.cloud.ibm.com"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// An invalid configuration keeps the last good one.
+	source.set("", "", errors.New("invalid configuration"))
+	configMap.ResourceVersion = "2"
+	_, err = client.CoreV1().ConfigMaps("kube-system").Update(ctx, configMap, metav1.UpdateOptions{})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return source.callCount() == 3 }, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, "eu-de.iaas.cloud.ibm.com", reloader.Current().RiaasEndpointURL.Host)
+
+	// Other objects don't trigger reloads.
+	other.ResourceVersion = "2"
+	_, err = client.CoreV1().ConfigMaps("kube-system").Update(ctx, other, metav1.UpdateOptions{})
+	assert.Nil(t, err)
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, 3, source.callCount())
+}
@@ -0,0 +1,195 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NodeLabelState ...
+type NodeLabelState string
+
+const (
+	// NodeLabelsCorrect means all the managed labels match the details from VPC.
+	NodeLabelsCorrect NodeLabelState = "correct"
+	// NodeLabelsMissing means some managed labels are missing and none has a different value.
+	NodeLabelsMissing NodeLabelState = "missing"
+	// NodeLabelsDrifted means some managed labels have a different value, or retired labels are still present.
+	NodeLabelsDrifted NodeLabelState = "drifted"
+)
+
+// NodeReport is the consistency of a single node with its VPC instance.
+type NodeReport struct {
+	Node       string         `json:"node"`
+	InstanceID string         `json:"instanceID"`
+	Zone       string         `json:"zone"`
+	Profile    string         `json:"profile"`
+	VPC        string         `json:"vpc"`
+	Labels     NodeLabelState `json:"labels"`
+	Drift      []string       `json:"drift,omitempty"`
+}
+
+// ClusterReport is the consistency of the labels of all the selected nodes with VPC.
+type ClusterReport struct {
+	Nodes []NodeReport `json:"nodes"`
+	// Orphaned lists the nodes whose instance no longer exists, as confirmed by VPC.
+	Orphaned []string `json:"orphaned"`
+	// Unresolved lists the nodes missing from the instance listing whose instance can't be confirmed, e.g. as
+	// they carry no instance ID label or the instance request failed.
+	Unresolved []string `json:"unresolved"`
+	// Zones counts the nodes per VPC zone.
+	Zones map[string]int `json:"zones"`
+}
+
+// Consistent returns true if all the nodes have an instance and correct labels. Unresolved nodes are not
+// known to be inconsistent.
+func (r ClusterReport) Consistent() bool {
+	if len(r.Orphaned) > 0 {
+		return false
+	}
+	for _, node := range r.Nodes {
+		if node.Labels != NodeLabelsCorrect {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteText writes the report as a table of the nodes followed by the orphaned and unresolved nodes and the node
+// count per zone.
+func (r ClusterReport) WriteText(w io.Writer) error {
+	// The tabwriter buffers the rows, write errors are returned by Flush.
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "NODE\tINSTANCE\tZONE\tPROFILE\tVPC\tLABELS\tDRIFT")
+	for _, node := range r.Nodes {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", node.Node, node.InstanceID, node.Zone, node.Profile, node.VPC, node.Labels, strings.Join(node.Drift, "; "))
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "\nOrphaned nodes: %d\n", len(r.Orphaned))
+	for _, node := range r.Orphaned {
+		fmt.Fprintf(&output, "  %s\n", node)
+	}
+	fmt.Fprintf(&output, "\nUnresolved nodes: %d\n", len(r.Unresolved))
+	for _, node := range r.Unresolved {
+		fmt.Fprintf(&output, "  %s\n", node)
+	}
+	zones := make([]string, 0, len(r.Zones))
+	for zone := range r.Zones {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	fmt.Fprintln(&output, "\nNodes per zone:")
+	for _, zone := range zones {
+		fmt.Fprintf(&output, "  %s: %d\n", zone, r.Zones[zone])
+	}
+	_, err := io.WriteString(w, output.String())
+	return err
+}
+
+// BuildClusterReport resolves every selected node against a single listing of the VPC instances, and compares
+// its labels with the labels the updater would set. The instance of a node missing from the listing is got by
+// its instance ID label, the node is only orphaned if VPC reports the instance as not found.
+func (c *VpcNodeLabelUpdater) BuildClusterReport(ctx context.Context, selector string) (*ClusterReport, error) {
+	nodes, err := c.K8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	instances, err := c.GetInstancesFromVPC(ctx, c.instanceListURL(url.Values{"limit": {instanceListPageLimit}}))
+	if err != nil {
+		return nil, err
+	}
+	instanceCache := NewInstanceCache(time.Hour)
+	instanceCache.Fill(instances)
+
+	report := &ClusterReport{Nodes: []NodeReport{}, Orphaned: []string{}, Unresolved: []string{}, Zones: map[string]int{}}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		instance, ok := instanceCache.Lookup(node.Name)
+		if !ok {
+			var orphaned bool
+			if instance, orphaned = c.confirmNodeInstance(ctx, node); orphaned {
+				report.Orphaned = append(report.Orphaned, node.Name)
+				continue
+			} else if instance == nil {
+				report.Unresolved = append(report.Unresolved, node.Name)
+				continue
+			}
+		}
+		nodeInfo := c.getNodeInfo(instance)
+		expected, retired, _ := c.expectedLabels(node, nodeInfo)
+		drifts := FindLabelDrift(node.Labels, expected, retired)
+
+		nodeReport := NodeReport{Node: node.Name, InstanceID: instance.ID, Zone: nodeInfo.Zone, Labels: NodeLabelsCorrect}
+		if instance.Profile != nil {
+			nodeReport.Profile = instance.Profile.Name
+		}
+		if instance.Vpc != nil {
+			nodeReport.VPC = instance.Vpc.Name
+		}
+		for _, drift := range drifts {
+			nodeReport.Drift = append(nodeReport.Drift, drift.String())
+			if !drift.Missing {
+				nodeReport.Labels = NodeLabelsDrifted
+			} else if nodeReport.Labels == NodeLabelsCorrect {
+				nodeReport.Labels = NodeLabelsMissing
+			}
+		}
+		report.Nodes = append(report.Nodes, nodeReport)
+		report.Zones[nodeInfo.Zone]++
+	}
+	sort.Slice(report.Nodes, func(i, j int) bool { return report.Nodes[i].Node < report.Nodes[j].Node })
+	sort.Strings(report.Orphaned)
+	sort.Strings(report.Unresolved)
+	return report, nil
+}
+
+// confirmNodeInstance gets the instance of a node missing from the instance listing by its instance ID label. It
+// returns true if VPC reports the instance as not found, and no instance if it can't be confirmed.
+func (c *VpcNodeLabelUpdater) confirmNodeInstance(ctx context.Context, node *v1.Node) (*Instance, bool) {
+	instanceID := node.Labels[instanceIDLabelKey]
+	if instanceID == "" {
+		c.Logger.Warn("No instance found for node without instance ID label", zap.String("workerNodeName", node.Name))
+		return nil, false
+	}
+	instance, err := c.getInstance(ctx, instanceID)
+	if errors.Is(err, errInstanceNotFound) {
+		c.Logger.Warn("Instance of node not found", zap.String("workerNodeName", node.Name), zap.String("instanceID", instanceID))
+		return nil, true
+	}
+	if err != nil {
+		c.Logger.Warn("Failed to get instance of node", zap.String("workerNodeName", node.Name), zap.String("instanceID", instanceID), zap.Error(err))
+		return nil, false
+	}
+	return instance, false
+}
@@ -0,0 +1,101 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestBuildClusterReport(t *testing.T) {
+	instances := testInstances()
+	for _, instance := range instances {
+		instance.Profile = &Profile{Name: "bx2-4x16"}
+		instance.Vpc = &Vpc{Name: "cluster-vpc"}
+	}
+	requests := 0
+	server := newFakeRiaasServer(t, instances, &requests)
+
+	updater := initNodeLabelUpdater(t)
+	updater.StorageSecretConfig.RiaasEndpointURL, _ = url.Parse(server.URL + "/v1/instances?generation=2")
+	correct, _ := updater.LabelSchemaOptions.desiredLabels(updater.getNodeInfo(instances[0]))
+	drifted, _ := updater.LabelSchemaOptions.desiredLabels(updater.getNodeInfo(instances[2]))
+	drifted[topologyZoneLabelKey] = "us-south-1"
+	updater.K8sClient = fake.NewSimpleClientset(
+		testNode("worker-1", correct),
+		testNode("10.0.0.2", nil),
+		testNode("worker-3", drifted),
+		testNode("worker-gone", map[string]string{instanceIDLabelKey: "instance-gone"}),
+		testNode("worker-unlabeled", nil),
+		// Renamed instances are got by the instance ID label.
+		testNode("worker-renamed", map[string]string{instanceIDLabelKey: "instance-1"}),
+	)
+
+	report, err := updater.BuildClusterReport(context.TODO(), "")
+	assert.Nil(t, err)
+	assert.False(t, report.Consistent())
+	assert.Equal(t, []string{"worker-gone"}, report.Orphaned)
+	assert.Equal(t, []string{"worker-unlabeled"}, report.Unresolved)
+	assert.Equal(t, map[string]int{"us-south-1": 2, "us-south-2": 1, "us-south-3": 1}, report.Zones)
+
+	states := map[string]NodeLabelState{}
+	for _, node := range report.Nodes {
+		states[node.Node] = node.Labels
+		assert.Equal(t, "bx2-4x16", node.Profile)
+		assert.Equal(t, "cluster-vpc", node.VPC)
+	}
+	assert.Equal(t, map[string]NodeLabelState{
+		"10.0.0.2":       NodeLabelsMissing,
+		"worker-1":       NodeLabelsCorrect,
+		"worker-3":       NodeLabelsDrifted,
+		"worker-renamed": NodeLabelsMissing,
+	}, states)
+	assert.Equal(t, "instance-2", report.Nodes[0].InstanceID)
+	assert.Equal(t, []string{topologyZoneLabelKey + `: "us-south-1", expected "us-south-3"`}, report.Nodes[2].Drift)
+
+	var output strings.Builder
+	assert.Nil(t, report.WriteText(&output))
+	assert.Contains(t, output.String(), "NODE            INSTANCE    ZONE        PROFILE   VPC          LABELS   DRIFT\n")
+	assert.Contains(t, output.String(), "Orphaned nodes: 1\n  worker-gone\n")
+	assert.Contains(t, output.String(), "Unresolved nodes: 1\n  worker-unlabeled\n")
+	assert.Contains(t, output.String(), "Nodes per zone:\n  us-south-1: 2\n  us-south-2: 1\n  us-south-3: 1\n")
+}
+
+func TestClusterReportConsistent(t *testing.T) {
+	testCases := []struct {
+		name     string
+		report   ClusterReport
+		expected bool
+	}{
+		{name: "Empty", report: ClusterReport{}, expected: true},
+		{name: "Correct", report: ClusterReport{Nodes: []NodeReport{{Node: "a", Labels: NodeLabelsCorrect}}}, expected: true},
+		{name: "Missing", report: ClusterReport{Nodes: []NodeReport{{Node: "a", Labels: NodeLabelsMissing}}}, expected: false},
+		{name: "Drifted", report: ClusterReport{Nodes: []NodeReport{{Node: "a", Labels: NodeLabelsDrifted}}}, expected: false},
+		{name: "Orphaned", report: ClusterReport{Orphaned: []string{"a"}}, expected: false},
+		{name: "Unresolved", report: ClusterReport{Unresolved: []string{"a"}}, expected: true},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		assert.Equal(t, tc.expected, tc.report.Consistent())
+	}
+}
@@ -0,0 +1,133 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// The labelSchemaVersionKey label carries the version of the label schema applied to the node. The
+// vpcBlockLabelKey sentinel label keeps the value "true", which node selectors match. Version 1 nodes were
+// labeled before schemas were versioned and only carry the sentinel.
+//
+// Schema history:
+//   - 1: worker-id, vpc-instance-id, failure-domain.beta region/zone and topology region/zone.
+//   - 2: vpc-instance-id and topology region/zone. worker-id and failure-domain.beta region/zone are
+//     legacy labels, written, kept or removed as configured by LabelSchemaOptions.
+const (
+	// CurrentLabelSchemaVersion ...
+	CurrentLabelSchemaVersion = 2
+
+	labelSchemaSentinel = "true"
+)
+
+// LegacyLabelMode ...
+type LegacyLabelMode string
+
+const (
+	// LegacyLabelWrite keeps writing the legacy labels.
+	LegacyLabelWrite LegacyLabelMode = "write"
+	// LegacyLabelKeep stops writing the legacy labels but leaves existing values on the nodes.
+	LegacyLabelKeep LegacyLabelMode = "keep"
+	// LegacyLabelRemove removes the legacy labels from the nodes.
+	LegacyLabelRemove LegacyLabelMode = "remove"
+)
+
+// ParseLegacyLabelMode ...
+func ParseLegacyLabelMode(mode string) (LegacyLabelMode, error) {
+	switch LegacyLabelMode(mode) {
+	case LegacyLabelWrite, LegacyLabelKeep, LegacyLabelRemove:
+		return LegacyLabelMode(mode), nil
+	}
+	return "", fmt.Errorf("invalid legacy label mode %q, must be one of %q, %q, %q", mode, LegacyLabelWrite, LegacyLabelKeep, LegacyLabelRemove)
+}
+
+// LabelSchemaOptions controls which legacy labels are still written during a transition window.
+// The zero value writes all the legacy labels.
+type LabelSchemaOptions struct {
+	WorkerIDLabel       LegacyLabelMode
+	FailureDomainLabels LegacyLabelMode
+}
+
+// LabelSchemaVersion returns the label schema version applied to a node, or 0 if the node was never labeled.
+func LabelSchemaVersion(labelMap map[string]string) int {
+	sentinel, ok := labelMap[vpcBlockLabelKey]
+	if !ok {
+		return 0
+	}
+	// Any other sentinel value is rewritten by the migration of version 1.
+	if sentinel != labelSchemaSentinel {
+		return 1
+	}
+	version, err := strconv.Atoi(labelMap[labelSchemaVersionKey])
+	if err != nil || version < 1 {
+		return 1
+	}
+	return version
+}
+
+// NeedsLabelSchemaMigration returns true if the node was labeled with an older label schema.
+func NeedsLabelSchemaMigration(labelMap map[string]string) bool {
+	return LabelSchemaVersion(labelMap) < CurrentLabelSchemaVersion
+}
+
+// desiredLabels returns the labels of the current schema to set for the given node details and
+// the retired label keys to remove from the node.
+func (o LabelSchemaOptions) desiredLabels(nodeinfo *NodeInfo) (map[string]string, []string) {
+	labels := map[string]string{
+		instanceIDLabelKey:     nodeinfo.InstanceID,
+		topologyRegionLabelKey: nodeinfo.Region,
+		topologyZoneLabelKey:   nodeinfo.Zone,
+		vpcBlockLabelKey:       labelSchemaSentinel,
+		labelSchemaVersionKey:  strconv.Itoa(CurrentLabelSchemaVersion),
+	}
+	var retired []string
+
+	legacy := []struct {
+		mode   LegacyLabelMode
+		labels map[string]string
+	}{
+		{
+			// TODO: remove worker-id label after its dependence is removed.
+			mode:   o.WorkerIDLabel,
+			labels: map[string]string{workerIDLabelKey: nodeinfo.InstanceID},
+		},
+		{
+			mode:   o.FailureDomainLabels,
+			labels: map[string]string{failureRegionLabelKey: nodeinfo.Region, failureZoneLabelKey: nodeinfo.Zone},
+		},
+	}
+	for _, l := range legacy {
+		switch l.mode {
+		case LegacyLabelKeep:
+			// Neither written nor removed, existing values are left to age out.
+		case LegacyLabelRemove:
+			for key := range l.labels {
+				retired = append(retired, key)
+			}
+		default:
+			for key, value := range l.labels {
+				labels[key] = value
+			}
+		}
+	}
+	sort.Strings(retired)
+	return labels, retired
+}
@@ -0,0 +1,111 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLabelSchemaVersion(t *testing.T) {
+	testCases := []struct {
+		name       string
+		labels     map[string]string
+		expVersion int
+	}{
+		{name: "not labeled", labels: map[string]string{}, expVersion: 0},
+		{name: "version 1", labels: map[string]string{vpcBlockLabelKey: "true"}, expVersion: 1},
+		{name: "version 2", labels: map[string]string{vpcBlockLabelKey: "true", labelSchemaVersionKey: "2"}, expVersion: 2},
+		{name: "version without sentinel", labels: map[string]string{labelSchemaVersionKey: "2"}, expVersion: 0},
+		{name: "sentinel carrying the version", labels: map[string]string{vpcBlockLabelKey: "v2"}, expVersion: 1},
+		{name: "invalid version", labels: map[string]string{vpcBlockLabelKey: "true", labelSchemaVersionKey: "v2"}, expVersion: 1},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		assert.Equal(t, tc.expVersion, LabelSchemaVersion(tc.labels))
+	}
+	assert.True(t, NeedsLabelSchemaMigration(map[string]string{vpcBlockLabelKey: "true"}))
+	assert.False(t, NeedsLabelSchemaMigration(map[string]string{vpcBlockLabelKey: "true", labelSchemaVersionKey: strconv.Itoa(CurrentLabelSchemaVersion)}))
+}
+
+func TestMigrateLabelSchema(t *testing.T) {
+	v1Labels := map[string]string{
+		"test":                 "test",
+		vpcBlockLabelKey:       "true",
+		workerIDLabelKey:       "instance-1",
+		instanceIDLabelKey:     "instance-1",
+		failureRegionLabelKey:  "us-south",
+		failureZoneLabelKey:    "us-south-1",
+		topologyRegionLabelKey: "us-south",
+		topologyZoneLabelKey:   "us-south-1",
+	}
+	testCases := []struct {
+		name      string
+		options   LabelSchemaOptions
+		expLabels []string
+		expAbsent []string
+	}{
+		{
+			name:      "write legacy labels",
+			options:   LabelSchemaOptions{},
+			expLabels: []string{workerIDLabelKey, failureRegionLabelKey, failureZoneLabelKey},
+		},
+		{
+			name:      "keep worker-id, remove failure-domain",
+			options:   LabelSchemaOptions{WorkerIDLabel: LegacyLabelKeep, FailureDomainLabels: LegacyLabelRemove},
+			expLabels: []string{workerIDLabelKey},
+			expAbsent: []string{failureRegionLabelKey, failureZoneLabelKey},
+		},
+		{
+			name:      "remove all legacy labels",
+			options:   LabelSchemaOptions{WorkerIDLabel: LegacyLabelRemove, FailureDomainLabels: LegacyLabelRemove},
+			expAbsent: []string{workerIDLabelKey, failureRegionLabelKey, failureZoneLabelKey},
+		},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		labels := map[string]string{}
+		for key, value := range v1Labels {
+			labels[key] = value
+		}
+		updater := initCachedNodeLabelUpdater(t, testNode("worker-1", labels))
+		updater.LabelSchemaOptions = tc.options
+
+		done, err := updater.UpdateNodeLabel(context.TODO(), "worker-1")
+		assert.Nil(t, err)
+		assert.True(t, done)
+
+		node, err := updater.K8sClient.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, "true", node.Labels[vpcBlockLabelKey])
+		assert.Equal(t, "2", node.Labels[labelSchemaVersionKey])
+		assert.Equal(t, "test", node.Labels["test"])
+		assert.Equal(t, "instance-1", node.Labels[instanceIDLabelKey])
+		for _, key := range tc.expLabels {
+			assert.Contains(t, node.Labels, key)
+		}
+		for _, key := range tc.expAbsent {
+			assert.NotContains(t, node.Labels, key)
+		}
+		assert.False(t, NeedsLabelSchemaMigration(node.Labels))
+	}
+}
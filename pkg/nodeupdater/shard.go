@@ -0,0 +1,235 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ShardGroupLabelKey marks the membership leases of the controller replicas sharing the nodes.
+	ShardGroupLabelKey = "vpc-node-label-updater.ibm-cloud.kubernetes.io/shard-group"
+
+	// shardRingReplicas is the number of points of every member on the hash ring. More points spread
+	// the nodes more evenly between the members.
+	shardRingReplicas = 64
+)
+
+// hashRing assigns keys to members by consistent hashing, so that a member joining or leaving
+// only moves the keys of that member.
+type hashRing struct {
+	points []uint64
+	owners map[uint64]string
+}
+
+// hashKey hashes with FNV-1a, whose output is then mixed as FNV alone spreads similar keys such as
+// node names poorly around the ring.
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key)) // #nosec G104: writing to a hash never fails.
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+func newHashRing(members []string) *hashRing {
+	ring := &hashRing{owners: map[uint64]string{}}
+	for _, member := range members {
+		for i := 0; i < shardRingReplicas; i++ {
+			point := hashKey(member + "#" + strconv.Itoa(i))
+			if _, ok := ring.owners[point]; ok {
+				continue
+			}
+			ring.owners[point] = member
+			ring.points = append(ring.points, point)
+		}
+	}
+	slices.Sort(ring.points)
+	return ring
+}
+
+// owner returns the member owning the key, empty if the ring has no members.
+func (r *hashRing) owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	point := hashKey(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= point })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+// ShardMembership splits the nodes between the active controller replicas. Every replica holds a
+// membership lease, which it renews, and owns the nodes the hash ring of the live members assigns to it.
+type ShardMembership struct {
+	K8sClient kubernetes.Interface
+	Logger    *zap.Logger
+	// Namespace and Group name the membership leases, which are called <Group>-<Identity>.
+	Namespace string
+	Group     string
+	Identity  string
+	// LeaseDuration is how long a member is considered live after renewing its lease.
+	LeaseDuration time.Duration
+	// RenewPeriod is how often the lease is renewed and the members are listed.
+	RenewPeriod time.Duration
+	// OnChange is optional. It is called when the members change, as nodes may have changed owners.
+	OnChange func()
+
+	mutex   sync.RWMutex
+	members []string
+	ring    *hashRing
+}
+
+// Owns returns true if the node is assigned to this replica. Nothing is owned before the first
+// listing of the members.
+func (s *ShardMembership) Owns(nodeName string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.ring != nil && s.ring.owner(nodeName) == s.Identity
+}
+
+// Members returns the live members found by the last listing.
+func (s *ShardMembership) Members() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return slices.Clone(s.members)
+}
+
+// Run renews the membership lease and tracks the members until the context is done, and then
+// deletes the lease so that the other members take over the nodes right away.
+func (s *ShardMembership) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.RenewPeriod)
+	defer ticker.Stop()
+	for {
+		if err := s.renew(ctx); err != nil {
+			s.Logger.Warn("Failed to renew shard membership lease", zap.String("identity", s.Identity), zap.Error(err))
+		} else if err := s.sync(ctx); err != nil {
+			s.Logger.Warn("Failed to list shard members", zap.String("group", s.Group), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			s.leave()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ShardMembership) leaseName() string {
+	return fmt.Sprintf("%s-%s", s.Group, s.Identity)
+}
+
+// renew creates or renews the membership lease of this replica.
+func (s *ShardMembership) renew(ctx context.Context) error {
+	leases := s.K8sClient.CoordinationV1().Leases(s.Namespace)
+	now := metav1.NewMicroTime(time.Now())
+	durationSeconds := int32(s.LeaseDuration.Seconds()) // #nosec G115: lease durations are far below the int32 range.
+	lease, err := leases.Get(ctx, s.leaseName(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: s.leaseName(), Namespace: s.Namespace, Labels: map[string]string{ShardGroupLabelKey: s.Group}},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &s.Identity,
+				LeaseDurationSeconds: &durationSeconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		_, err = leases.Create(ctx, lease, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	lease.Spec.HolderIdentity = &s.Identity
+	lease.Spec.LeaseDurationSeconds = &durationSeconds
+	lease.Spec.RenewTime = &now
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// sync lists the live members and rebuilds the hash ring if they changed.
+func (s *ShardMembership) sync(ctx context.Context) error {
+	leases, err := s.K8sClient.CoordinationV1().Leases(s.Namespace).List(ctx, metav1.ListOptions{LabelSelector: ShardGroupLabelKey + "=" + s.Group})
+	if err != nil {
+		return err
+	}
+	members := liveShardMembers(leases.Items, time.Now())
+
+	s.mutex.Lock()
+	changed := !slices.Equal(members, s.members)
+	if changed {
+		s.members = members
+		s.ring = newHashRing(members)
+	}
+	s.mutex.Unlock()
+
+	if changed {
+		s.Logger.Info("Shard members changed", zap.String("group", s.Group), zap.Strings("members", members))
+		if s.OnChange != nil {
+			s.OnChange()
+		}
+	}
+	return nil
+}
+
+// leave deletes the membership lease of this replica.
+func (s *ShardMembership) leave() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.RenewPeriod)
+	defer cancel()
+	err := s.K8sClient.CoordinationV1().Leases(s.Namespace).Delete(ctx, s.leaseName(), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		s.Logger.Warn("Failed to delete shard membership lease", zap.String("identity", s.Identity), zap.Error(err))
+	}
+}
+
+// liveShardMembers returns the sorted identities of the leases renewed within their duration.
+func liveShardMembers(leases []coordinationv1.Lease, now time.Time) []string {
+	var members []string
+	for _, lease := range leases {
+		spec := lease.Spec
+		if spec.HolderIdentity == nil || spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		if now.After(spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second)) {
+			continue
+		}
+		members = append(members, *spec.HolderIdentity)
+	}
+	sort.Strings(members)
+	return members
+}
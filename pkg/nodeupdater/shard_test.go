@@ -0,0 +1,98 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestHashRing(t *testing.T) {
+	ring := newHashRing([]string{"a", "b", "c"})
+	owned := map[string]int{}
+	owners := map[string]string{}
+	for i := 0; i < 300; i++ {
+		node := fmt.Sprintf("worker-%d", i)
+		owners[node] = ring.owner(node)
+		owned[owners[node]]++
+	}
+	for _, member := range []string{"a", "b", "c"} {
+		assert.Greater(t, owned[member], 50, "member %s owns too few nodes", member)
+	}
+
+	// Removing a member only moves the nodes it owned.
+	ring = newHashRing([]string{"a", "b"})
+	for node, owner := range owners {
+		if owner != "c" {
+			assert.Equal(t, owner, ring.owner(node))
+		}
+	}
+	assert.Equal(t, "", newHashRing(nil).owner("worker-1"))
+}
+
+func TestLiveShardMembers(t *testing.T) {
+	now := time.Now()
+	lease := func(identity string, renewedAgo time.Duration) coordinationv1.Lease {
+		renewTime := metav1.NewMicroTime(now.Add(-renewedAgo))
+		duration := int32(15)
+		return coordinationv1.Lease{Spec: coordinationv1.LeaseSpec{HolderIdentity: &identity, RenewTime: &renewTime, LeaseDurationSeconds: &duration}}
+	}
+	members := liveShardMembers([]coordinationv1.Lease{lease("b", time.Second), lease("expired", time.Minute), lease("a", 0), {}}, now)
+	assert.Equal(t, []string{"a", "b"}, members)
+}
+
+func TestShardMembership(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	k8sClient := fake.NewSimpleClientset()
+	newMembership := func(identity string) *ShardMembership {
+		return &ShardMembership{K8sClient: k8sClient, Logger: logger, Namespace: "kube-system", Group: "updater",
+			Identity: identity, LeaseDuration: time.Minute, RenewPeriod: time.Hour}
+	}
+	a, b := newMembership("a"), newMembership("b")
+	assert.False(t, a.Owns("worker-1"))
+
+	changes := 0
+	a.OnChange = func() { changes++ }
+	assert.Nil(t, a.renew(context.TODO()))
+	assert.Nil(t, b.renew(context.TODO()))
+	assert.Nil(t, a.sync(context.TODO()))
+	assert.Nil(t, b.sync(context.TODO()))
+	assert.Equal(t, []string{"a", "b"}, a.Members())
+	assert.Equal(t, 1, changes)
+
+	for i := 0; i < 20; i++ {
+		node := fmt.Sprintf("worker-%d", i)
+		assert.NotEqual(t, a.Owns(node), b.Owns(node), "node %s must have exactly one owner", node)
+	}
+
+	// A member leaving hands its nodes over.
+	b.leave()
+	assert.Nil(t, a.sync(context.TODO()))
+	assert.Equal(t, []string{"a"}, a.Members())
+	assert.Equal(t, 2, changes)
+	assert.True(t, a.Owns("worker-1"))
+}
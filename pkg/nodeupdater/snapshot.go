@@ -0,0 +1,387 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// LabelSnapshotAnnotationKey holds the label snapshot of a node when snapshots are stored in annotations.
+	LabelSnapshotAnnotationKey = "vpc-node-label-updater.ibm-cloud.kubernetes.io/label-snapshot"
+
+	// labelSnapshotVersion is the version of the snapshot format written by this release.
+	// Snapshots of all the versions up to this one can be read.
+	labelSnapshotVersion = 1
+
+	// SnapshotStoreAnnotation saves label snapshots in a node annotation.
+	SnapshotStoreAnnotation = "annotation"
+	// SnapshotStoreConfigMap saves label snapshots in a ConfigMap keyed by node name.
+	SnapshotStoreConfigMap = "configmap"
+	// SnapshotStoreNone disables label snapshots.
+	SnapshotStoreNone = "none"
+)
+
+// LabelSnapshot is the label set of a node saved before the updater changed it.
+type LabelSnapshot struct {
+	Version int               `json:"version"`
+	Node    string            `json:"node"`
+	TakenAt time.Time         `json:"takenAt"`
+	Labels  map[string]string `json:"labels"`
+	// ManagedLabels is the managed labels annotation of the node when the snapshot was taken, if any.
+	ManagedLabels *string `json:"managedLabels,omitempty"`
+	// ChangedLabels are the label keys the updater changed after the snapshot was taken. It is nil in
+	// snapshots written by older releases.
+	ChangedLabels []string `json:"changedLabels"`
+}
+
+// newLabelSnapshot takes a snapshot of the labels and managed labels annotation of a node.
+func newLabelSnapshot(node *v1.Node) *LabelSnapshot {
+	snapshot := &LabelSnapshot{
+		Version: labelSnapshotVersion,
+		Node:    node.Name,
+		TakenAt: time.Now().UTC(),
+		Labels:  make(map[string]string, len(node.ObjectMeta.Labels)),
+	}
+	for key, value := range node.ObjectMeta.Labels {
+		snapshot.Labels[key] = value
+	}
+	if managed, ok := node.ObjectMeta.Annotations[ManagedLabelsAnnotationKey]; ok {
+		snapshot.ManagedLabels = &managed
+	}
+	return snapshot
+}
+
+// decodeLabelSnapshot decodes a snapshot of any supported format version.
+func decodeLabelSnapshot(data string) (*LabelSnapshot, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal([]byte(data), &header); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal label snapshot: %v", err)
+	}
+	switch header.Version {
+	case 1:
+		var snapshot LabelSnapshot
+		if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal label snapshot: %v", err)
+		}
+		return &snapshot, nil
+	}
+	return nil, fmt.Errorf("unsupported label snapshot version %d, this release supports versions up to %d", header.Version, labelSnapshotVersion)
+}
+
+// recordChanges records the label keys which differ between the snapshot and the node.
+func (s *LabelSnapshot) recordChanges(node *v1.Node) {
+	s.ChangedLabels = []string{}
+	for key, value := range s.Labels {
+		if current, ok := node.ObjectMeta.Labels[key]; !ok || current != value {
+			s.ChangedLabels = append(s.ChangedLabels, key)
+		}
+	}
+	for key := range node.ObjectMeta.Labels {
+		if _, ok := s.Labels[key]; !ok {
+			s.ChangedLabels = append(s.ChangedLabels, key)
+		}
+	}
+	sort.Strings(s.ChangedLabels)
+}
+
+// changedKeys returns the label keys changed by the updater after the snapshot was taken. For snapshots of
+// older releases, which don't record them, these are the keys managed by the updater then or now.
+func (s *LabelSnapshot) changedKeys(node *v1.Node) []string {
+	if s.ChangedLabels != nil {
+		return s.ChangedLabels
+	}
+	managed, _ := GetManagedLabels(node) // #nosec G104: an invalid annotation lists no keys.
+	if s.ManagedLabels != nil {
+		var previous ManagedLabels
+		if err := json.Unmarshal([]byte(*s.ManagedLabels), &previous); err == nil {
+			for key, label := range previous {
+				managed[key] = label
+			}
+		}
+	}
+	keys := make([]string, 0, len(managed))
+	for key := range managed {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// restoreOn restores the labels changed by the updater and the managed labels annotation of the node from the
+// snapshot. Labels set by others since the snapshot was taken are kept.
+func (s *LabelSnapshot) restoreOn(node *v1.Node) {
+	keys := s.changedKeys(node)
+	if node.ObjectMeta.Labels == nil {
+		node.ObjectMeta.Labels = map[string]string{}
+	}
+	for _, key := range keys {
+		if value, ok := s.Labels[key]; ok {
+			node.ObjectMeta.Labels[key] = value
+			continue
+		}
+		delete(node.ObjectMeta.Labels, key)
+	}
+	if s.ManagedLabels == nil {
+		delete(node.ObjectMeta.Annotations, ManagedLabelsAnnotationKey)
+		return
+	}
+	if node.ObjectMeta.Annotations == nil {
+		node.ObjectMeta.Annotations = map[string]string{}
+	}
+	node.ObjectMeta.Annotations[ManagedLabelsAnnotationKey] = *s.ManagedLabels
+}
+
+// SnapshotStore saves and loads the label snapshots of nodes.
+type SnapshotStore interface {
+	// Save saves the snapshot of the node. The node object is passed so that the store can save the snapshot
+	// in it, to be written along with the label update.
+	Save(ctx context.Context, node *v1.Node, snapshot *LabelSnapshot) error
+	// Load returns the saved snapshot of the node, or nil if there is none.
+	Load(ctx context.Context, node *v1.Node) (*LabelSnapshot, error)
+	// Delete removes the saved snapshot of the node.
+	Delete(ctx context.Context, node *v1.Node) error
+}
+
+// inNode returns true if the store keeps snapshots in the node object, so that saving and deleting them is
+// written along with the node update. Other stores are only changed once the node update succeeded, so that
+// a failed update neither replaces the last good snapshot nor loses it.
+func inNode(store SnapshotStore) bool {
+	_, ok := store.(AnnotationSnapshotStore)
+	return ok
+}
+
+// AnnotationSnapshotStore saves label snapshots in a node annotation.
+type AnnotationSnapshotStore struct{}
+
+// Save ...
+func (AnnotationSnapshotStore) Save(ctx context.Context, node *v1.Node, snapshot *LabelSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if node.ObjectMeta.Annotations == nil {
+		node.ObjectMeta.Annotations = map[string]string{}
+	}
+	node.ObjectMeta.Annotations[LabelSnapshotAnnotationKey] = string(data)
+	return nil
+}
+
+// Load ...
+func (AnnotationSnapshotStore) Load(ctx context.Context, node *v1.Node) (*LabelSnapshot, error) {
+	data, ok := node.ObjectMeta.Annotations[LabelSnapshotAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+	return decodeLabelSnapshot(data)
+}
+
+// Delete ...
+func (AnnotationSnapshotStore) Delete(ctx context.Context, node *v1.Node) error {
+	delete(node.ObjectMeta.Annotations, LabelSnapshotAnnotationKey)
+	return nil
+}
+
+// ConfigMapSnapshotStore saves label snapshots in a ConfigMap keyed by node name.
+type ConfigMapSnapshotStore struct {
+	K8sClient kubernetes.Interface
+	Namespace string
+	Name      string
+}
+
+// Save ...
+func (s *ConfigMapSnapshotStore) Save(ctx context.Context, node *v1.Node, snapshot *LabelSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return s.update(ctx, func(cm *v1.ConfigMap) {
+		cm.Data[node.Name] = string(data)
+	})
+}
+
+// Load ...
+func (s *ConfigMapSnapshotStore) Load(ctx context.Context, node *v1.Node) (*LabelSnapshot, error) {
+	cm, err := s.K8sClient.CoreV1().ConfigMaps(s.Namespace).Get(ctx, s.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, ok := cm.Data[node.Name]
+	if !ok {
+		return nil, nil
+	}
+	return decodeLabelSnapshot(data)
+}
+
+// Delete ...
+func (s *ConfigMapSnapshotStore) Delete(ctx context.Context, node *v1.Node) error {
+	return s.update(ctx, func(cm *v1.ConfigMap) {
+		delete(cm.Data, node.Name)
+	})
+}
+
+// update applies a change to the snapshot ConfigMap, creating it if needed. Nodes are labeled concurrently,
+// so the change is retried on conflicts.
+func (s *ConfigMapSnapshotStore) update(ctx context.Context, change func(cm *v1.ConfigMap)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := s.K8sClient.CoreV1().ConfigMaps(s.Namespace).Get(ctx, s.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			cm = &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: s.Name, Namespace: s.Namespace}, Data: map[string]string{}}
+			change(cm)
+			_, err = s.K8sClient.CoreV1().ConfigMaps(s.Namespace).Create(ctx, cm, metav1.CreateOptions{})
+			if errors.IsAlreadyExists(err) {
+				return errors.NewConflict(v1.Resource("configmaps"), s.Name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		change(cm)
+		_, err = s.K8sClient.CoreV1().ConfigMaps(s.Namespace).Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// NewSnapshotStore returns the snapshot store of the given kind, or nil for SnapshotStoreNone. The namespace
+// and name of the ConfigMap are only used by SnapshotStoreConfigMap.
+func NewSnapshotStore(kind string, k8sClient kubernetes.Interface, namespace, name string) (SnapshotStore, error) {
+	switch kind {
+	case SnapshotStoreAnnotation:
+		return AnnotationSnapshotStore{}, nil
+	case SnapshotStoreConfigMap:
+		if name == "" {
+			return nil, fmt.Errorf("a configmap name is required to store label snapshots in a configmap")
+		}
+		return &ConfigMapSnapshotStore{K8sClient: k8sClient, Namespace: namespace, Name: name}, nil
+	case SnapshotStoreNone:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("invalid snapshot store %q, must be one of %q, %q, %q", kind, SnapshotStoreAnnotation, SnapshotStoreConfigMap, SnapshotStoreNone)
+}
+
+// updateNodeWithSnapshot writes the updater's node and saves the snapshot taken before its labels were changed,
+// if any. A snapshot which can't be saved once the node is updated is logged, the labels stay updated.
+func (c *VpcNodeLabelUpdater) updateNodeWithSnapshot(ctx context.Context, snapshot *LabelSnapshot) error {
+	if c.SnapshotStore == nil || snapshot == nil {
+		return c.updateNode(ctx)
+	}
+	snapshot.recordChanges(c.Node)
+	if inNode(c.SnapshotStore) {
+		if err := c.SnapshotStore.Save(ctx, c.Node, snapshot); err != nil {
+			return fmt.Errorf("failed to save label snapshot of node %s: %v", c.Node.Name, err)
+		}
+		return c.updateNode(ctx)
+	}
+	if err := c.updateNode(ctx); err != nil {
+		return err
+	}
+	if err := c.SnapshotStore.Save(ctx, c.Node, snapshot); err != nil {
+		c.Logger.Error("Failed to save label snapshot, the node labels can't be rolled back", zap.String("workerNodeName", c.Node.Name), zap.Error(err))
+	}
+	return nil
+}
+
+// RollbackOptions ...
+type RollbackOptions struct {
+	// Node is the name of the node to roll back. Takes precedence over Selector.
+	Node string
+	// Selector is a label selector restricting the nodes to roll back. Empty selects all nodes.
+	Selector string
+	// DryRun only logs the labels the nodes would be rolled back to.
+	DryRun bool
+}
+
+// RollbackNodes restores the labels of the selected nodes from their snapshots, and returns the number of
+// nodes rolled back. Nodes without a snapshot are skipped.
+func RollbackNodes(ctx context.Context, k8sClient kubernetes.Interface, store SnapshotStore, options RollbackOptions, logger *zap.Logger) (int, error) {
+	var nodeNames []string
+	if options.Node != "" {
+		nodeNames = []string{options.Node}
+	} else {
+		nodes, err := k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: options.Selector})
+		if err != nil {
+			return 0, err
+		}
+		for _, node := range nodes.Items {
+			nodeNames = append(nodeNames, node.Name)
+		}
+	}
+
+	rolledBack := 0
+	for _, nodeName := range nodeNames {
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			node, err := k8sClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			snapshot, err := store.Load(ctx, node)
+			if err != nil {
+				return err
+			}
+			if snapshot == nil {
+				logger.Info("Node has no label snapshot, skipping", zap.String("node", nodeName))
+				return nil
+			}
+			if options.DryRun {
+				logger.Info("Dry run, not rolling back node", zap.String("node", nodeName), zap.Time("snapshotTakenAt", snapshot.TakenAt), zap.Reflect("labels", snapshot.Labels))
+				rolledBack++
+				return nil
+			}
+			snapshot.restoreOn(node)
+			if inNode(store) {
+				if err = store.Delete(ctx, node); err != nil {
+					return err
+				}
+			}
+			if _, err = k8sClient.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{}); err != nil {
+				return err
+			}
+			if !inNode(store) {
+				if err = store.Delete(ctx, node); err != nil {
+					return fmt.Errorf("rolled back node labels but failed to delete label snapshot: %v", err)
+				}
+			}
+			logger.Info("Rolled back node labels", zap.String("node", nodeName), zap.Time("snapshotTakenAt", snapshot.TakenAt))
+			rolledBack++
+			return nil
+		})
+		if err != nil {
+			return rolledBack, fmt.Errorf("failed to roll back node %s: %v", nodeName, err)
+		}
+	}
+	return rolledBack, nil
+}
@@ -0,0 +1,141 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestDecodeLabelSnapshot(t *testing.T) {
+	testCases := []struct {
+		name   string
+		data   string
+		expErr bool
+	}{
+		{name: "version 1", data: `{"version":1,"node":"worker-1","labels":{"a":"b"}}`},
+		{name: "newer version", data: `{"version":2,"node":"worker-1","labels":{"a":"b"}}`, expErr: true},
+		{name: "missing version", data: `{"node":"worker-1"}`, expErr: true},
+		{name: "invalid json", data: `{`, expErr: true},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		snapshot, err := decodeLabelSnapshot(tc.data)
+		if tc.expErr {
+			assert.NotNil(t, err)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"a": "b"}, snapshot.Labels)
+	}
+}
+
+func TestRollbackNodes(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	testCases := []struct {
+		name      string
+		store     string
+		options   RollbackOptions
+		expLabels map[string]string
+	}{
+		{name: "annotation", store: SnapshotStoreAnnotation, options: RollbackOptions{Node: "worker-1"},
+			expLabels: map[string]string{"test": "test", "other": "added-later", topologyZoneLabelKey: "kubelet-zone"}},
+		{name: "configmap", store: SnapshotStoreConfigMap, options: RollbackOptions{Selector: "test=test"},
+			expLabels: map[string]string{"test": "test", "other": "added-later", topologyZoneLabelKey: "kubelet-zone"}},
+		{name: "dry run", store: SnapshotStoreAnnotation, options: RollbackOptions{Node: "worker-1", DryRun: true}},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		updater := initCachedNodeLabelUpdater(t, testNode("worker-1", map[string]string{"test": "test", topologyZoneLabelKey: "kubelet-zone"}))
+		store, err := NewSnapshotStore(tc.store, updater.K8sClient, "kube-system", "snapshots")
+		assert.Nil(t, err)
+		updater.SnapshotStore = store
+		_, err = updater.UpdateNodeLabel(context.TODO(), "worker-1")
+		assert.Nil(t, err)
+		// Labels set by others after the snapshot are kept.
+		node, _ := updater.K8sClient.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+		node.ObjectMeta.Labels["other"] = "added-later"
+		_, err = updater.K8sClient.CoreV1().Nodes().Update(context.TODO(), node, metav1.UpdateOptions{})
+		assert.Nil(t, err)
+
+		rolledBack, err := RollbackNodes(context.TODO(), updater.K8sClient, store, tc.options, logger)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, rolledBack)
+		node, err = updater.K8sClient.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+		assert.Nil(t, err)
+		if tc.expLabels == nil {
+			assert.Equal(t, "us-south-1", node.ObjectMeta.Labels[topologyZoneLabelKey])
+			continue
+		}
+		assert.Equal(t, tc.expLabels, node.ObjectMeta.Labels)
+		assert.NotContains(t, node.ObjectMeta.Annotations, ManagedLabelsAnnotationKey)
+		snapshot, err := store.Load(context.TODO(), node)
+		assert.Nil(t, err)
+		assert.Nil(t, snapshot)
+	}
+}
+
+func TestNewSnapshotStore(t *testing.T) {
+	store, err := NewSnapshotStore(SnapshotStoreNone, nil, "", "")
+	assert.Nil(t, err)
+	assert.Nil(t, store)
+	_, err = NewSnapshotStore(SnapshotStoreConfigMap, nil, "kube-system", "")
+	assert.NotNil(t, err)
+	_, err = NewSnapshotStore("etcd", nil, "", "")
+	assert.NotNil(t, err)
+}
+
+func TestSnapshotFailedNodeUpdate(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	updater := initCachedNodeLabelUpdater(t, testNode("worker-1", map[string]string{"test": "test", topologyZoneLabelKey: "kubelet-zone"}))
+	store, _ := NewSnapshotStore(SnapshotStoreConfigMap, updater.K8sClient, "kube-system", "snapshots")
+	updater.SnapshotStore = store
+	_, err := updater.UpdateNodeLabel(context.TODO(), "worker-1")
+	assert.Nil(t, err)
+	node, _ := updater.K8sClient.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+	saved, _ := store.Load(context.TODO(), node)
+	if !assert.NotNil(t, saved) {
+		return
+	}
+
+	// A failed node update neither replaces the snapshot nor deletes it.
+	updater.K8sClient.(*fake.Clientset).PrependReactor("update", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("update failed")
+	})
+	updater.Node = node
+	updater.DriftPolicy = DriftPolicyCorrect
+	updater.Node.ObjectMeta.Labels[topologyZoneLabelKey] = "drifted"
+	_, err = updater.VerifyNodeLabel(context.TODO(), "worker-1")
+	assert.NotNil(t, err)
+	_, err = RollbackNodes(context.TODO(), updater.K8sClient, store, RollbackOptions{Node: "worker-1"}, logger)
+	assert.NotNil(t, err)
+	snapshot, err := store.Load(context.TODO(), node)
+	assert.Nil(t, err)
+	assert.Equal(t, saved, snapshot)
+}
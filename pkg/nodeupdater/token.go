@@ -0,0 +1,77 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenRefreshMargin is how long before its expiry a cached IAM token is replaced.
+const tokenRefreshMargin = 5 * time.Minute
+
+// TokenSource caches the IAM token of the secret provider, which fetches a new token on every call,
+// and fetches a new one shortly before the cached token expires. A long running controller needs it,
+// as the token read at start up expires after an hour.
+type TokenSource struct {
+	fetch func() (string, uint64, error)
+
+	mutex     sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// NewTokenSource returns a token source calling fetch for new tokens. fetch returns the token and its
+// lifetime in seconds.
+func NewTokenSource(fetch func() (string, uint64, error)) *TokenSource {
+	return &TokenSource{fetch: fetch}
+}
+
+// Token returns the cached token, fetching a new one if it is about to expire.
+func (t *TokenSource) Token(ctx context.Context) (string, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.token != "" && time.Now().Before(t.expiresAt.Add(-tokenRefreshMargin)) {
+		return t.token, nil
+	}
+	_, span := StartSpan(ctx, "FetchIAMToken")
+	token, lifetime, err := t.fetch()
+	EndSpan(span, err)
+	if err != nil {
+		return "", err
+	}
+	t.token = token
+	t.expiresAt = time.Now().Add(time.Duration(lifetime) * time.Second) // #nosec G115: token lifetimes are far below the int64 range.
+	return token, nil
+}
+
+// ExpiresAt returns the expiry of the cached token, zero if no token was fetched yet.
+func (t *TokenSource) ExpiresAt() time.Time {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.expiresAt
+}
+
+// AccessToken returns the IAM token for VPC requests, from the token source if set.
+func (s *StorageSecretConfig) AccessToken(ctx context.Context) (string, error) {
+	if s.TokenSource == nil {
+		return s.IAMAccessToken, nil
+	}
+	return s.TokenSource.Token(ctx)
+}
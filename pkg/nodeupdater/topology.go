@@ -0,0 +1,90 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+)
+
+// FailureDomainLabelsAuto selects the legacy failure-domain label mode from the Kubernetes server version.
+const FailureDomainLabelsAuto = "auto"
+
+// FailureDomainPolicy decides whether the deprecated failure-domain.beta.kubernetes.io labels are
+// written, kept or removed.
+type FailureDomainPolicy struct {
+	// Mode is one of the legacy label modes, or FailureDomainLabelsAuto.
+	Mode string
+	// KeepFromVersion is the server version from which auto stops writing the labels.
+	KeepFromVersion string
+	// RemoveFromVersion is the server version from which auto removes the labels. Empty means never.
+	RemoveFromVersion string
+}
+
+// Validate ...
+func (p FailureDomainPolicy) Validate() error {
+	if p.Mode != FailureDomainLabelsAuto {
+		_, err := ParseLegacyLabelMode(p.Mode)
+		return err
+	}
+	if _, err := version.ParseGeneric(p.KeepFromVersion); err != nil {
+		return fmt.Errorf("invalid failure-domain keep version %q: %v", p.KeepFromVersion, err)
+	}
+	if p.RemoveFromVersion == "" {
+		return nil
+	}
+	if _, err := version.ParseGeneric(p.RemoveFromVersion); err != nil {
+		return fmt.Errorf("invalid failure-domain remove version %q: %v", p.RemoveFromVersion, err)
+	}
+	return nil
+}
+
+// ResolveFailureDomainLabelMode returns the legacy label mode of the failure-domain labels. In auto mode the
+// server version is read with the discovery client. If it cannot be read, the labels are kept as they are.
+func ResolveFailureDomainLabelMode(client discovery.ServerVersionInterface, policy FailureDomainPolicy, logger *zap.Logger) LegacyLabelMode {
+	if policy.Mode != FailureDomainLabelsAuto {
+		mode := LegacyLabelMode(policy.Mode)
+		logger.Info("Using configured failure-domain label mode", zap.String("mode", string(mode)))
+		return mode
+	}
+
+	serverInfo, err := client.ServerVersion()
+	if err != nil {
+		logger.Warn("Failed to get kubernetes server version, keeping failure-domain labels as they are", zap.Error(err))
+		return LegacyLabelKeep
+	}
+	serverVersion, err := version.ParseGeneric(serverInfo.GitVersion)
+	if err != nil {
+		logger.Warn("Failed to parse kubernetes server version, keeping failure-domain labels as they are", zap.String("serverVersion", serverInfo.GitVersion), zap.Error(err))
+		return LegacyLabelKeep
+	}
+
+	mode := LegacyLabelWrite
+	if serverVersion.AtLeast(version.MustParseGeneric(policy.KeepFromVersion)) {
+		mode = LegacyLabelKeep
+	}
+	if policy.RemoveFromVersion != "" && serverVersion.AtLeast(version.MustParseGeneric(policy.RemoveFromVersion)) {
+		mode = LegacyLabelRemove
+	}
+	logger.Info("Selected failure-domain label mode from kubernetes server version", zap.String("serverVersion", serverVersion.String()),
+		zap.String("keepFromVersion", policy.KeepFromVersion), zap.String("removeFromVersion", policy.RemoveFromVersion), zap.String("mode", string(mode)))
+	return mode
+}
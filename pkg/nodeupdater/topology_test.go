@@ -0,0 +1,88 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestResolveFailureDomainLabelMode(t *testing.T) {
+	testCases := []struct {
+		name          string
+		serverVersion string
+		policy        FailureDomainPolicy
+		expMode       LegacyLabelMode
+	}{
+		{
+			name:          "configured mode",
+			serverVersion: "v1.35.1",
+			policy:        FailureDomainPolicy{Mode: "write"},
+			expMode:       LegacyLabelWrite,
+		},
+		{
+			name:          "auto before keep version",
+			serverVersion: "v1.16.3",
+			policy:        FailureDomainPolicy{Mode: "auto", KeepFromVersion: "1.17", RemoveFromVersion: "1.30"},
+			expMode:       LegacyLabelWrite,
+		},
+		{
+			name:          "auto after keep version",
+			serverVersion: "v1.29.0+IKS",
+			policy:        FailureDomainPolicy{Mode: "auto", KeepFromVersion: "1.17", RemoveFromVersion: "1.30"},
+			expMode:       LegacyLabelKeep,
+		},
+		{
+			name:          "auto after remove version",
+			serverVersion: "v1.30.2",
+			policy:        FailureDomainPolicy{Mode: "auto", KeepFromVersion: "1.17", RemoveFromVersion: "1.30"},
+			expMode:       LegacyLabelRemove,
+		},
+		{
+			name:          "auto without remove version",
+			serverVersion: "v1.35.0",
+			policy:        FailureDomainPolicy{Mode: "auto", KeepFromVersion: "1.17"},
+			expMode:       LegacyLabelKeep,
+		},
+		{
+			name:          "unparsable server version",
+			serverVersion: "unknown",
+			policy:        FailureDomainPolicy{Mode: "auto", KeepFromVersion: "1.17"},
+			expMode:       LegacyLabelKeep,
+		},
+	}
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		assert.Nil(t, tc.policy.Validate())
+		discovery := fake.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery)
+		discovery.FakedServerVersion = &version.Info{GitVersion: tc.serverVersion}
+		assert.Equal(t, tc.expMode, ResolveFailureDomainLabelMode(discovery, tc.policy, logger))
+	}
+}
+
+func TestValidateFailureDomainPolicy(t *testing.T) {
+	assert.NotNil(t, FailureDomainPolicy{Mode: "sometimes"}.Validate())
+	assert.NotNil(t, FailureDomainPolicy{Mode: "auto", KeepFromVersion: "latest"}.Validate())
+	assert.NotNil(t, FailureDomainPolicy{Mode: "auto", KeepFromVersion: "1.17", RemoveFromVersion: "x"}.Validate())
+}
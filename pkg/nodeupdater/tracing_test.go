@@ -0,0 +1,102 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// setUpTestTracing installs a tracer provider recording the ended spans until the end of the test.
+func setUpTestTracing(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestTraceInstanceLookup(t *testing.T) {
+	recorder := setUpTestTracing(t)
+	requests := 0
+	server := newFakeRiaasServer(t, testInstances(), &requests)
+	var traceParents []string
+	handler := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParents = append(traceParents, r.Header.Get("traceparent"))
+		handler.ServeHTTP(w, r)
+	})
+
+	updater := initNodeLabelUpdater(t)
+	updater.StorageSecretConfig.RiaasEndpointURL, _ = url.Parse(server.URL + "/v1/instances?generation=2")
+	updater.InstanceCache = NewInstanceCache(time.Minute)
+
+	ctx, span := StartSpan(context.Background(), "test")
+	_, err := updater.GetWorkerDetails(ctx, "worker-2")
+	span.End()
+	assert.Nil(t, err)
+
+	traceID := span.SpanContext().TraceID()
+	names := map[string]int{}
+	for _, ended := range recorder.Ended() {
+		assert.Equal(t, traceID, ended.SpanContext().TraceID(), ended.Name())
+		names[ended.Name()]++
+	}
+	assert.Equal(t, map[string]int{
+		"test":              1,
+		"GetWorkerDetails":  1,
+		"ListInstances":     1,
+		"GetInstancePage":   3,
+		"GET /v1/instances": 3,
+	}, names)
+
+	// Every page request carries the trace context of its attempt span.
+	assert.Equal(t, 3, len(traceParents))
+	for _, traceParent := range traceParents {
+		assert.Contains(t, traceParent, traceID.String())
+	}
+}
+
+func TestEndSpan(t *testing.T) {
+	recorder := setUpTestTracing(t)
+
+	_, span := StartSpan(context.Background(), "failed")
+	EndSpan(span, errors.New("request to https://10.1.2.3/v1/instances failed"))
+
+	ended := recorder.Ended()
+	assert.Equal(t, 1, len(ended))
+	assert.Equal(t, codes.Error, ended[0].Status().Code)
+	assert.NotContains(t, ended[0].Status().Description, "10.1.2.3")
+	assert.Equal(t, 1, len(ended[0].Events()))
+}
@@ -18,6 +18,7 @@
 package nodeupdater
 
 import (
+	"context"
 	"encoding/json"
 	errors "errors"
 	"fmt"
@@ -31,7 +32,11 @@ import (
 	"github.com/IBM/ibmcloud-volume-interface/provider/iam"
 	sp "github.com/IBM/secret-common-lib/pkg/secret_provider"
 	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
@@ -46,10 +51,47 @@ const (
 	maxAttempts            = 30
 	retryInterval          = "10s"
 	vpcBlockLabelKey       = "vpc-block-csi-driver-labels"
+	labelSchemaVersionKey  = "vpc-block-csi-driver-labels-version"
+	instanceListPageLimit  = "100"
 )
 
+// SecretConfigSource sets up the RIAAS endpoint and the IAM token source of the updater, without fetching a token.
+type SecretConfigSource func() (*StorageSecretConfig, error)
+
+// SecretProviderSource reads the secret configuration with the secret provider, from the storage-secret-store
+// secret of the cluster.
+func SecretProviderSource(k8sClient *k8s_utils.KubernetesClient, ctxLogger *zap.Logger) SecretConfigSource {
+	return func() (*StorageSecretConfig, error) {
+		return newStorageSecretConfig(k8sClient, ctxLogger)
+	}
+}
+
 // ReadSecretConfiguration ...
-func ReadSecretConfiguration(k8sClient *k8s_utils.KubernetesClient, ctxLogger *zap.Logger) (*StorageSecretConfig, error) {
+func ReadSecretConfiguration(ctx context.Context, k8sClient *k8s_utils.KubernetesClient, ctxLogger *zap.Logger) (*StorageSecretConfig, error) {
+	return ReadSecretConfigurationFrom(ctx, SecretProviderSource(k8sClient, ctxLogger), ctxLogger)
+}
+
+// ReadSecretConfigurationFrom reads the secret configuration of source and fetches the first IAM token.
+func ReadSecretConfigurationFrom(ctx context.Context, source SecretConfigSource, ctxLogger *zap.Logger) (_ *StorageSecretConfig, err error) {
+	ctx, span := StartSpan(ctx, "ReadSecretConfiguration")
+	defer func() { EndSpan(span, err) }()
+
+	storageSecretConfig, err := source()
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := storageSecretConfig.TokenSource.Token(ctx)
+	if err != nil {
+		ctxLogger.Error("Failed to Get IAM access token", zap.Error(err))
+		return nil, err
+	}
+	storageSecretConfig.IAMAccessToken = accessToken
+	return storageSecretConfig, nil
+}
+
+// newStorageSecretConfig reads the RIAAS endpoint from the secret provider and sets up the IAM token
+// source, without fetching a token.
+func newStorageSecretConfig(k8sClient *k8s_utils.KubernetesClient, ctxLogger *zap.Logger) (*StorageSecretConfig, error) {
 	ctxLogger.Info("Fetching secret configuration.")
 	providerType := map[string]string{
 		sp.ProviderType: sp.VPC,
@@ -66,28 +108,28 @@ func ReadSecretConfiguration(k8sClient *k8s_utils.KubernetesClient, ctxLogger *z
 		return nil, err
 	}
 
-	// Correct if the G2EndpointURL is of the form "http://".
-	riaasURL = getEndpointURL(riaasURL, ctxLogger)
-	riaasInstanceURL, err := url.Parse(fmt.Sprintf("%s/v1/instances?generation=%s&version=%s", riaasURL, vpcGeneration, vpcRiaasVersion))
+	endpoint, err := normalizeEndpointURL(riaasURL, ctxLogger)
 	if err != nil {
-		ctxLogger.Error("Failed to parse riassInstanceURL", zap.Error(err))
+		ctxLogger.Error("Invalid RIAAS endpoint", zap.Error(err))
 		return nil, err
 	}
 	storageSecretConfig := &StorageSecretConfig{
-		RiaasEndpointURL: riaasInstanceURL,
+		RiaasEndpointURL: instancesURL(endpoint),
 	}
 
-	accessToken, _, err := spObject.GetDefaultIAMToken(false, "vpc-node-label-updater")
-	if err != nil {
-		ctxLogger.Error("Failed to Get IAM access token", zap.Error(err))
-		return nil, err
-	}
-	storageSecretConfig.IAMAccessToken = accessToken
+	storageSecretConfig.TokenSource = NewTokenSource(func() (string, uint64, error) {
+		return spObject.GetDefaultIAMToken(false, "vpc-node-label-updater")
+	})
 	return storageSecretConfig, nil
 }
 
 // ErrorRetry ...
 func ErrorRetry(logger *zap.Logger, funcToRetry func() (error, bool)) error {
+	return ErrorRetryWithContext(context.Background(), logger, funcToRetry)
+}
+
+// ErrorRetryWithContext is ErrorRetry giving up when the context is done, with the last error.
+func ErrorRetryWithContext(ctx context.Context, logger *zap.Logger, funcToRetry func() (error, bool)) error {
 	var err error
 	var shouldStop bool
 	retryIntervaltime, err := time.ParseDuration(retryInterval)
@@ -107,7 +149,13 @@ func ErrorRetry(logger *zap.Logger, funcToRetry func() (error, bool)) error {
 		if i >= (maxAttempts - 1) {
 			break
 		}
-		time.Sleep(retryIntervaltime)
+		timer := time.NewTimer(retryIntervaltime)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
 		logger.Warn("retrying after Error:", zap.Error(err))
 	}
 	//error set by name above so no need to explicitly return it
@@ -127,72 +175,230 @@ func CheckIfRequiredLabelsPresent(labelMap map[string]string) bool {
 	return false
 }
 
-// getEndpointURL corrects endpoint url if it is of form "http://"
-func getEndpointURL(url string, logger *zap.Logger) string {
-	if strings.Contains(url, "http://") {
-		logger.Warn("Token exchange endpoint URL is of the form 'http' instead 'https'. Correcting it for valid request.", zap.Reflect("Endpoint URL: ", url))
-		return strings.Replace(url, "http", "https", 1)
+// normalizeEndpointURL parses an endpoint URL of the secret configuration. URLs without scheme get https and
+// the http scheme is corrected to https. Other schemes, credentials, queries and invalid hosts are rejected.
+func normalizeEndpointURL(endpoint string, logger *zap.Logger) (*url.URL, error) {
+	raw := strings.TrimSpace(endpoint)
+	if raw == "" {
+		return nil, errors.New("endpoint URL is empty")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint URL: %v", err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+	case "http":
+		logger.Warn("Endpoint URL is of the form 'http' instead 'https'. Correcting it for valid request.", zap.String("endpointURL", u.Redacted()))
+	default:
+		return nil, fmt.Errorf("invalid scheme %q of endpoint URL %s, https is required", u.Scheme, u.Redacted())
+	}
+	if u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return nil, errors.New("endpoint URL must not hold credentials, a query or a fragment")
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" || (net.ParseIP(host) == nil && len(validation.IsDNS1123Subdomain(host)) > 0) {
+		return nil, fmt.Errorf("invalid host %q of endpoint URL", u.Hostname())
+	}
+	return &url.URL{Scheme: "https", Host: strings.ToLower(u.Host), Path: strings.TrimSuffix(u.Path, "/")}, nil
+}
+
+// instancesURL returns the URL of the instance list of the RIAAS endpoint.
+func instancesURL(endpoint *url.URL) *url.URL {
+	return &url.URL{
+		Scheme:   endpoint.Scheme,
+		Host:     endpoint.Host,
+		Path:     endpoint.Path + "/v1/instances",
+		RawQuery: url.Values{"generation": {vpcGeneration}, "version": {vpcRiaasVersion}}.Encode(),
 	}
-	return url
 }
 
 // GetWorkerDetails ...
-func (c *VpcNodeLabelUpdater) GetWorkerDetails(workerNodeName string) (*NodeInfo, error) {
+func (c *VpcNodeLabelUpdater) GetWorkerDetails(ctx context.Context, workerNodeName string) (nodeInfo *NodeInfo, err error) {
+	ctx, span := StartSpan(ctx, "GetWorkerDetails", attribute.Bool("instanceCache", c.InstanceCache != nil))
+	defer func() { EndSpan(span, err) }()
+	if c.InstanceCache != nil {
+		instance, err := c.LookupInstance(ctx, workerNodeName)
+		if err != nil {
+			return nil, err
+		}
+		return c.getNodeInfo(instance), nil
+	}
 	if net.ParseIP(workerNodeName) == nil {
 		c.Logger.Info("Worker Node Name is not in ip format. Getting instance detail by name from vpc provider")
-		return c.GetInstanceByName(workerNodeName)
+		return c.GetInstanceByName(ctx, workerNodeName)
 	}
 	c.Logger.Info("Worker Node Name is in ip format. Getting instance detail by ipv4 from vpc provider")
-	return c.GetInstanceByIP(workerNodeName)
+	return c.GetInstanceByIP(ctx, workerNodeName)
+}
+
+// LookupInstance returns the instance of the worker node from the instance cache. The cache is
+// refreshed with a single listing of all instances if it has expired or does not know the worker.
+func (c *VpcNodeLabelUpdater) LookupInstance(ctx context.Context, workerNodeName string) (*Instance, error) {
+	if instance, ok := c.InstanceCache.Lookup(workerNodeName); ok {
+		c.Logger.Info("Found instance in instance cache", zap.String("workerNodeName", workerNodeName))
+		return instance, nil
+	}
+	if err := c.RefreshInstanceCache(ctx); err != nil {
+		return nil, err
+	}
+	if instance, ok := c.InstanceCache.Lookup(workerNodeName); ok {
+		return instance, nil
+	}
+	return nil, fmt.Errorf("failed to get worker details, worker with name %s was not found in the instanceList fetched from vpc provider", workerNodeName)
+}
+
+// RefreshInstanceCache lists all the instances from VPC and replaces the content of the instance cache.
+func (c *VpcNodeLabelUpdater) RefreshInstanceCache(ctx context.Context) error {
+	c.Logger.Info("Refreshing instance cache from VPC provider")
+	instanceList, err := c.GetInstancesFromVPC(ctx, c.instanceListURL(url.Values{"limit": {instanceListPageLimit}}))
+	if err != nil {
+		return err
+	}
+	c.InstanceCache.Fill(instanceList)
+	c.Logger.Info("Refreshed instance cache", zap.Int("instanceCount", len(instanceList)))
+	return nil
+}
+
+// instanceListURL returns a copy of the RIAAS instances URL with the instance list filter and the given query
+// parameters set.
+func (c *VpcNodeLabelUpdater) instanceListURL(params url.Values) *url.URL {
+	riaasInstanceURL := *c.SecretConfig().RiaasEndpointURL
+	q := riaasInstanceURL.Query()
+	c.InstanceListFilter.apply(q)
+	for key, values := range params {
+		q[key] = values
+	}
+	riaasInstanceURL.RawQuery = q.Encode()
+	return &riaasInstanceURL
 }
 
-// GetInstancesFromVPC ...
-func (c *VpcNodeLabelUpdater) GetInstancesFromVPC(riaasInstanceURL *url.URL) ([]*Instance, error) {
+// GetInstancesFromVPC lists the instances from VPC, following the pagination links of the response.
+func (c *VpcNodeLabelUpdater) GetInstancesFromVPC(ctx context.Context, riaasInstanceURL *url.URL) (instances []*Instance, err error) {
 	c.Logger.Info("Getting instance List from VPC provider")
+	ctx, span := StartSpan(ctx, "ListInstances")
+	defer func() {
+		span.SetAttributes(attribute.Int("instanceCount", len(instances)))
+		EndSpan(span, err)
+	}()
+
+	for page, pageURL := 1, riaasInstanceURL; pageURL != nil; page++ {
+		instanceList, err := c.getInstancePage(ctx, pageURL, page)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, instanceList.Instances...)
 
-	instanceReq := &http.Request{
-		Method: "GET",
-		URL:    riaasInstanceURL,
-		Header: map[string][]string{
-			"Content-Type":  {"application/json"},
-			"Accept":        {"application/json"},
-			"Authorization": {c.StorageSecretConfig.IAMAccessToken},
-		},
+		pageURL = nil
+		if instanceList.Next != nil && instanceList.Next.Href != "" {
+			if pageURL, err = url.Parse(instanceList.Next.Href); err != nil {
+				return nil, fmt.Errorf("failed to parse next page of instances: %v", err)
+			}
+		}
 	}
-	var instanceResponse *http.Response
-	var err error
+	if len(instances) == 0 {
+		return nil, errors.New("failed to get worker details as instance list is empty")
+	}
+	return instances, nil
+}
 
-	err = ErrorRetry(c.Logger, func() (error, bool) {
-		instanceResponse, err = http.DefaultClient.Do(instanceReq) //nolint
-		return err, !iam.IsConnectionError(err)                    // Skip retry if its not connection error
-	})
+// getInstancePage fetches a single page of the instance list.
+func (c *VpcNodeLabelUpdater) getInstancePage(ctx context.Context, pageURL *url.URL, page int) (_ *InstanceList, err error) {
+	ctx, span := StartSpan(ctx, "GetInstancePage", attribute.Int("page", page))
+	defer func() { EndSpan(span, err) }()
 
+	instance, _, err := c.getFromRIAAS(ctx, pageURL, "GET /v1/instances")
 	if err != nil {
 		return nil, err
 	}
+	var instanceList InstanceList
+	err = json.Unmarshal(instance, &instanceList)
+	if err != nil {
+		return nil, errors.New("failed to unmarshal json response of instances")
+	}
+	return &instanceList, nil
+}
+
+// getFromRIAAS sends an authenticated GET request to RIAAS, retrying on connection errors, and returns the
+// response body and status code.
+func (c *VpcNodeLabelUpdater) getFromRIAAS(ctx context.Context, requestURL *url.URL, spanName string) ([]byte, int, error) {
+	secretConfig := c.SecretConfig()
+	accessToken, err := secretConfig.AccessToken(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	var instanceResponse *http.Response
+	attempt := 0
+	err = ErrorRetryWithContext(ctx, c.Logger, func() (error, bool) {
+		attempt++
+		attemptCtx, attemptSpan := StartSpan(ctx, spanName, attribute.Int("attempt", attempt))
+		instanceReq := (&http.Request{
+			Method: "GET",
+			URL:    requestURL,
+			Header: map[string][]string{
+				"Content-Type":  {"application/json"},
+				"Accept":        {"application/json"},
+				"Authorization": {accessToken},
+			},
+		}).WithContext(attemptCtx)
+		otel.GetTextMapPropagator().Inject(attemptCtx, propagation.HeaderCarrier(instanceReq.Header))
+		instanceResponse, err = secretConfig.httpClient().Do(instanceReq) //nolint
+		if err == nil {
+			attemptSpan.SetAttributes(attribute.Int("http.response.status_code", instanceResponse.StatusCode))
+		}
+		EndSpan(attemptSpan, err)
+		return err, !iam.IsConnectionError(err) // Skip retry if its not connection error
+	})
+
+	if err != nil {
+		return nil, 0, err
+	}
 	defer instanceResponse.Body.Close()
 	// read response body
-	instance, err := io.ReadAll(instanceResponse.Body)
+	body, err := io.ReadAll(instanceResponse.Body)
 	if err != nil {
 		c.Logger.Error("Failed to read response body of instance details from riaas provider", zap.Error(err))
-		return nil, err
+		return nil, 0, err
 	}
-	var instanceList InstanceList
-	err = json.Unmarshal(instance, &instanceList)
+	return body, instanceResponse.StatusCode, nil
+}
+
+// errInstanceNotFound is returned when VPC reports an instance as not found.
+var errInstanceNotFound = errors.New("instance not found")
+
+// getInstance gets a single instance by ID. It returns errInstanceNotFound if the instance doesn't exist, and
+// drops it from the instance cache then.
+func (c *VpcNodeLabelUpdater) getInstance(ctx context.Context, instanceID string) (*Instance, error) {
+	instanceURL := *c.SecretConfig().RiaasEndpointURL
+	instanceURL.Path = instanceURL.Path + "/" + url.PathEscape(instanceID)
+	body, status, err := c.getFromRIAAS(ctx, &instanceURL, "GET /v1/instances/{id}")
 	if err != nil {
-		return nil, errors.New("failed to unmarshal json response of instances")
+		return nil, err
 	}
-	if len(instanceList.Instances) == 0 {
-		return nil, errors.New("failed to get worker details as instance list is empty")
+	if status == http.StatusNotFound {
+		if c.InstanceCache != nil {
+			c.InstanceCache.InvalidateInstance(instanceID)
+		}
+		return nil, fmt.Errorf("failed to get instance %s: %w", instanceID, errInstanceNotFound)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("failed to get instance %s, status %d", instanceID, status)
+	}
+	var instance Instance
+	if err := json.Unmarshal(body, &instance); err != nil {
+		return nil, errors.New("failed to unmarshal json response of instance")
 	}
-	return instanceList.Instances, nil
+	return &instance, nil
 }
 
 // GetInstanceByIP ...
-func (c *VpcNodeLabelUpdater) GetInstanceByIP(workerNodeName string) (*NodeInfo, error) {
+func (c *VpcNodeLabelUpdater) GetInstanceByIP(ctx context.Context, workerNodeName string) (*NodeInfo, error) {
 	c.Logger.Info("Getting InstanceList from VPC provider...")
 
-	instanceList, err := c.GetInstancesFromVPC(c.StorageSecretConfig.RiaasEndpointURL)
+	instanceList, err := c.GetInstancesFromVPC(ctx, c.instanceListURL(url.Values{"limit": {instanceListPageLimit}}))
 	if err != nil {
 		return nil, err
 	}
@@ -200,7 +406,8 @@ func (c *VpcNodeLabelUpdater) GetInstanceByIP(workerNodeName string) (*NodeInfo,
 	for _, instanceItem := range instanceList {
 		// Check if worker IP is matching with requested worker node name
 		if instanceItem.PrimaryNetworkInterface.PrimaryIpv4Address == workerNodeName {
-			c.Logger.Info("Successfully found instance", zap.Reflect("instanceDetail", instanceItem))
+			c.Logger.Info("Successfully found instance", zap.String("instanceID", instanceItem.ID), zap.String("instanceName", instanceItem.Name))
+			c.Logger.Debug("Instance details", zap.Reflect("instanceDetail", instanceItem))
 			return c.getNodeInfo(instanceItem), nil
 		}
 	}
@@ -209,15 +416,11 @@ func (c *VpcNodeLabelUpdater) GetInstanceByIP(workerNodeName string) (*NodeInfo,
 }
 
 // GetInstanceByName ...
-func (c *VpcNodeLabelUpdater) GetInstanceByName(workerNodeName string) (*NodeInfo, error) {
+func (c *VpcNodeLabelUpdater) GetInstanceByName(ctx context.Context, workerNodeName string) (*NodeInfo, error) {
 	c.Logger.Info("Getting InstanceList from VPC provider...")
 
-	riaasInstanceURL := c.StorageSecretConfig.RiaasEndpointURL
-	q := riaasInstanceURL.Query()
-	q.Add("name", workerNodeName)
-	c.StorageSecretConfig.RiaasEndpointURL.RawQuery = q.Encode()
-
-	instanceList, err := c.GetInstancesFromVPC(riaasInstanceURL)
+	riaasInstanceURL := c.instanceListURL(url.Values{"name": {workerNodeName}})
+	instanceList, err := c.GetInstancesFromVPC(ctx, riaasInstanceURL)
 	if err != nil {
 		return nil, err
 	}
@@ -235,6 +438,7 @@ func (c *VpcNodeLabelUpdater) getNodeInfo(instance *Instance) *NodeInfo {
 		InstanceID: insID,
 		Zone:       zone,
 		Region:     region,
+		Instance:   instance,
 	}
 	c.Logger.Info("Successfully fetched node detail from VPC provider", zap.Reflect("nodeDetails", nodeDetails))
 	return nodeDetails
@@ -0,0 +1,168 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+)
+
+// DriftPolicy ...
+type DriftPolicy string
+
+const (
+	// DriftPolicyCorrect overwrites drifted labels with the values resolved from VPC.
+	DriftPolicyCorrect DriftPolicy = "correct"
+	// DriftPolicyReport only logs drifted labels and records them as node events.
+	DriftPolicyReport DriftPolicy = "report"
+
+	eventReasonLabelDrift          = "LabelDrift"
+	eventReasonLabelDriftCorrected = "LabelDriftCorrected"
+)
+
+// ParseDriftPolicy ...
+func ParseDriftPolicy(policy string) (DriftPolicy, error) {
+	switch DriftPolicy(policy) {
+	case DriftPolicyCorrect, DriftPolicyReport:
+		return DriftPolicy(policy), nil
+	}
+	return "", fmt.Errorf("invalid drift policy %q, must be one of %q, %q", policy, DriftPolicyCorrect, DriftPolicyReport)
+}
+
+// LabelDrift describes a managed label whose value differs from the one resolved from VPC,
+// or a retired label still present on the node.
+type LabelDrift struct {
+	Key      string
+	Expected string
+	Actual   string
+	Missing  bool
+	Retired  bool
+}
+
+// String ...
+func (d LabelDrift) String() string {
+	if d.Retired {
+		return fmt.Sprintf("%s: %q, expected to be removed", d.Key, d.Actual)
+	}
+	if d.Missing {
+		return fmt.Sprintf("%s: missing, expected %q", d.Key, d.Expected)
+	}
+	return fmt.Sprintf("%s: %q, expected %q", d.Key, d.Actual, d.Expected)
+}
+
+// FindLabelDrift compares the node labels with the expected managed labels and the retired label
+// keys, and returns the differences sorted by label key.
+func FindLabelDrift(labels map[string]string, expected map[string]string, retired []string) []LabelDrift {
+	var drifts []LabelDrift
+	for _, key := range retired {
+		if actual, ok := labels[key]; ok {
+			drifts = append(drifts, LabelDrift{Key: key, Actual: actual, Retired: true})
+		}
+	}
+	for key, expectedValue := range expected {
+		actual, ok := labels[key]
+		if ok && actual == expectedValue {
+			continue
+		}
+		drifts = append(drifts, LabelDrift{Key: key, Expected: expectedValue, Actual: actual, Missing: !ok})
+	}
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i].Key < drifts[j].Key })
+	return drifts
+}
+
+// VerifyNodeLabel resolves the node details from VPC and compares every managed label value with them.
+// Drifted labels are corrected or only reported, depending on the drift policy. Corrections of labels set to
+// a different value are subject to the conflict policies. The detected drift is returned in both cases.
+func (c *VpcNodeLabelUpdater) VerifyNodeLabel(ctx context.Context, workerNodeName string) (_ []LabelDrift, err error) {
+	ctx, span := StartSpan(ctx, "VerifyNodeLabel")
+	defer func() { EndSpan(span, err) }()
+
+	nodeinfo, err := c.GetWorkerDetails(ctx, workerNodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	expected, retired, policies := c.expectedLabels(c.Node, nodeinfo)
+	drifts := FindLabelDrift(c.Node.ObjectMeta.Labels, expected, retired)
+	snapshot := newLabelSnapshot(c.Node)
+	managed := c.managedLabels()
+	// The report policy leaves the node unchanged, labels are only adopted when correcting.
+	adopted := c.DriftPolicy == DriftPolicyCorrect && managed.adopt(c.Node, expected)
+	if len(drifts) == 0 {
+		c.Logger.Info("Node labels match the details from VPC provider", zap.String("workerNodeName", workerNodeName))
+	}
+
+	var corrections []LabelDrift
+	for _, drift := range drifts {
+		if c.DriftPolicy != DriftPolicyCorrect {
+			c.Logger.Warn("Detected label drift", zap.String("workerNodeName", workerNodeName), zap.Stringer("drift", drift))
+			c.recordEvent(v1.EventTypeWarning, eventReasonLabelDrift, "Label %s", drift)
+			continue
+		}
+		if drift.Retired {
+			managed.removeLabel(c.Node, drift.Key)
+			corrections = append(corrections, drift)
+			continue
+		}
+		apply, err := c.resolveLabelConflict(managed, drift.Key, drift.Expected)
+		if err != nil {
+			return drifts, err
+		}
+		if apply {
+			managed.setLabel(c.Node, drift.Key, drift.Expected)
+			corrections = append(corrections, drift)
+		}
+	}
+	annotated := false
+	if c.DriftPolicy == DriftPolicyCorrect {
+		if annotated, err = c.applyPolicyAnnotations(managed, policies); err != nil {
+			return drifts, err
+		}
+	}
+	if len(corrections) == 0 && !adopted && !annotated {
+		return drifts, nil
+	}
+
+	if err = managed.setOn(c.Node); err != nil {
+		return drifts, err
+	}
+	// Adopting labels leaves their values unchanged, there is nothing to roll back.
+	if len(corrections) == 0 {
+		snapshot = nil
+	}
+	if err = c.updateNodeWithSnapshot(ctx, snapshot); err != nil {
+		return drifts, err
+	}
+	for _, drift := range corrections {
+		c.Logger.Info("Corrected drifted label", zap.String("workerNodeName", workerNodeName), zap.Stringer("drift", drift))
+		c.recordEvent(v1.EventTypeNormal, eventReasonLabelDriftCorrected, "Corrected label %s", drift)
+	}
+	return drifts, nil
+}
+
+// recordEvent records an event on the node if an event recorder is configured.
+func (c *VpcNodeLabelUpdater) recordEvent(eventType, reason, messageFmt string, args ...interface{}) {
+	if c.Recorder == nil {
+		return
+	}
+	c.Recorder.Eventf(c.Node, eventType, reason, messageFmt, args...)
+}
@@ -0,0 +1,135 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+// initCachedNodeLabelUpdater returns an updater backed by a fake clientset holding the node and
+// an instance cache filled with testInstances, so that no request is sent to VPC.
+func initCachedNodeLabelUpdater(t *testing.T, node *v1.Node) *VpcNodeLabelUpdater {
+	updater := initNodeLabelUpdater(t)
+	updater.Node = node
+	updater.K8sClient = fake.NewSimpleClientset(node)
+	updater.InstanceCache = NewInstanceCache(time.Minute)
+	updater.InstanceCache.Fill(testInstances())
+	updater.Recorder = record.NewFakeRecorder(10)
+	return updater
+}
+
+func testNode(name string, labels map[string]string) *v1.Node {
+	return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestFindLabelDrift(t *testing.T) {
+	expected := map[string]string{"a": "1", "b": "2", "c": "3"}
+	drifts := FindLabelDrift(map[string]string{"a": "1", "b": "x", "d": "4"}, expected, []string{"d", "e"})
+	assert.Equal(t, []LabelDrift{
+		{Key: "b", Expected: "2", Actual: "x"},
+		{Key: "c", Expected: "3", Missing: true},
+		{Key: "d", Actual: "4", Retired: true},
+	}, drifts)
+	assert.Empty(t, FindLabelDrift(expected, expected, nil))
+}
+
+func TestParseDriftPolicy(t *testing.T) {
+	policy, err := ParseDriftPolicy("report")
+	assert.Nil(t, err)
+	assert.Equal(t, DriftPolicyReport, policy)
+	_, err = ParseDriftPolicy("ignore")
+	assert.NotNil(t, err)
+}
+
+func TestVerifyNodeLabel(t *testing.T) {
+	labeled, _ := LabelSchemaOptions{}.desiredLabels(&NodeInfo{InstanceID: "instance-1", Region: "us-south", Zone: "us-south-1"})
+
+	testCases := []struct {
+		name      string
+		policy    DriftPolicy
+		zone      string
+		expDrifts int
+		expZone   string
+		expEvent  string
+	}{
+		{
+			name:    "no drift",
+			policy:  DriftPolicyCorrect,
+			zone:    "us-south-1",
+			expZone: "us-south-1",
+		},
+		{
+			name:      "drift corrected",
+			policy:    DriftPolicyCorrect,
+			zone:      "us-south-2",
+			expDrifts: 1,
+			expZone:   "us-south-1",
+			expEvent:  "Normal " + eventReasonLabelDriftCorrected,
+		},
+		{
+			name:      "drift reported",
+			policy:    DriftPolicyReport,
+			zone:      "us-south-2",
+			expDrifts: 1,
+			expZone:   "us-south-2",
+			expEvent:  "Warning " + eventReasonLabelDrift,
+		},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		labels := map[string]string{}
+		for key, value := range labeled {
+			labels[key] = value
+		}
+		labels[topologyZoneLabelKey] = tc.zone
+		updater := initCachedNodeLabelUpdater(t, testNode("worker-1", labels))
+		updater.DriftPolicy = tc.policy
+
+		drifts, err := updater.VerifyNodeLabel(context.TODO(), "worker-1")
+		assert.Nil(t, err)
+		assert.Len(t, drifts, tc.expDrifts)
+
+		node, err := updater.K8sClient.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expZone, node.Labels[topologyZoneLabelKey])
+
+		events := drainEvents(updater.Recorder.(*record.FakeRecorder))
+		if tc.expEvent == "" {
+			assert.Empty(t, events)
+			continue
+		}
+		assert.Contains(t, strings.Join(events, "\n"), tc.expEvent)
+	}
+}
+
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	return events
+}
@@ -0,0 +1,117 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// WithHTTPClient sets the HTTP client of the secret configurations of source. The IAM token requests of
+// CredentialsSource and LocalSecretSource use it too, the secret provider requests its tokens with its own client.
+func WithHTTPClient(source SecretConfigSource, client *http.Client) SecretConfigSource {
+	return func() (*StorageSecretConfig, error) {
+		storageSecretConfig, err := source()
+		if err != nil {
+			return nil, err
+		}
+		storageSecretConfig.HTTPClient = client
+		return storageSecretConfig, nil
+	}
+}
+
+// httpClient returns the client of the RIAAS and IAM requests.
+func (s *StorageSecretConfig) httpClient() *http.Client {
+	if s.HTTPClient != nil {
+		return s.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// VPCTLSOptions configures the TLS of the VPC requests, e.g. to trust an inspecting egress proxy.
+type VPCTLSOptions struct {
+	// CABundleFile is a PEM file of CA certificates trusted in addition to the system ones.
+	CABundleFile string
+	// ClientCertFile and ClientKeyFile are the PEM files of the optional client certificate.
+	ClientCertFile string
+	ClientKeyFile  string
+	// MinVersion is the minimum TLS version, 1.2 or 1.3. Empty is 1.2.
+	MinVersion string
+}
+
+// tlsVersions are the accepted minimum TLS versions.
+var tlsVersions = map[string]uint16{
+	"":    tls.VersionTLS12,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// IsSet returns true if any option differs from the defaults of http.DefaultClient.
+func (o VPCTLSOptions) IsSet() bool {
+	return o.CABundleFile != "" || o.ClientCertFile != "" || o.ClientKeyFile != "" || (o.MinVersion != "" && o.MinVersion != "1.2")
+}
+
+// TLSConfig returns the TLS configuration of the options.
+func (o VPCTLSOptions) TLSConfig() (*tls.Config, error) {
+	minVersion, ok := tlsVersions[o.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("invalid minimum TLS version %q, must be 1.2 or 1.3", o.MinVersion)
+	}
+	config := &tls.Config{MinVersion: minVersion}
+	if o.CABundleFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		data, err := os.ReadFile(o.CABundleFile) // #nosec G304: the file is given by the operator.
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("CA bundle %s holds no PEM certificate", o.CABundleFile)
+		}
+		config.RootCAs = pool
+	}
+	if (o.ClientCertFile == "") != (o.ClientKeyFile == "") {
+		return nil, errors.New("client certificate and key must be set together")
+	}
+	if o.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.ClientCertFile, o.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// NewVPCHTTPClient returns a client of the VPC requests using the TLS options, and the proxy of the environment
+// like http.DefaultClient.
+func NewVPCHTTPClient(options VPCTLSOptions) (*http.Client, error) {
+	config, err := options.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	return &http.Client{Transport: transport}, nil
+}
@@ -0,0 +1,125 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeClientCertificate writes a self-signed client certificate and its key to dir.
+func writeClientCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "vpc-node-label-updater"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	assert.Nil(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+func TestNewVPCHTTPClient(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeClientCertificate(t, dir)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+		}
+	})
+	// The server of the proxy CA requires a client certificate and TLS 1.2.
+	server := httptest.NewUnstartedServer(handler)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+	caFile := filepath.Join(dir, "ca.pem")
+	assert.Nil(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+	invalidFile := filepath.Join(dir, "invalid.pem")
+	assert.Nil(t, os.WriteFile(invalidFile, []byte("not a certificate"), 0600))
+
+	testCases := []struct {
+		name          string
+		options       VPCTLSOptions
+		expClientErr  bool
+		expRequestErr bool
+	}{
+		{name: "CA bundle and client certificate", options: VPCTLSOptions{CABundleFile: caFile, ClientCertFile: certFile, ClientKeyFile: keyFile, MinVersion: "1.2"}},
+		{name: "Untrusted proxy CA", options: VPCTLSOptions{ClientCertFile: certFile, ClientKeyFile: keyFile}, expRequestErr: true},
+		{name: "Missing client certificate", options: VPCTLSOptions{CABundleFile: caFile}, expRequestErr: true},
+		{name: "TLS 1.3 required", options: VPCTLSOptions{CABundleFile: caFile, ClientCertFile: certFile, ClientKeyFile: keyFile, MinVersion: "1.3"}, expRequestErr: true},
+		{name: "Invalid TLS version", options: VPCTLSOptions{MinVersion: "1.1"}, expClientErr: true},
+		{name: "Missing CA bundle", options: VPCTLSOptions{CABundleFile: filepath.Join(dir, "missing.pem")}, expClientErr: true},
+		{name: "CA bundle without certificate", options: VPCTLSOptions{CABundleFile: invalidFile}, expClientErr: true},
+		{name: "Client certificate without key", options: VPCTLSOptions{ClientCertFile: certFile}, expClientErr: true},
+		{name: "Invalid client key", options: VPCTLSOptions{ClientCertFile: certFile, ClientKeyFile: invalidFile}, expClientErr: true},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		client, err := NewVPCHTTPClient(tc.options)
+		assert.Equal(t, tc.expClientErr, err != nil)
+		if err != nil {
+			continue
+		}
+		resp, err := client.Get(server.URL)
+		assert.Equal(t, tc.expRequestErr, err != nil)
+		if err == nil {
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			_ = resp.Body.Close()
+		}
+	}
+
+	assert.False(t, VPCTLSOptions{MinVersion: "1.2"}.IsSet())
+	assert.True(t, VPCTLSOptions{MinVersion: "1.3"}.IsSet())
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	client := &http.Client{}
+	source := func() (*StorageSecretConfig, error) { return &StorageSecretConfig{}, nil }
+	config, err := WithHTTPClient(source, client)()
+	assert.Nil(t, err)
+	assert.Equal(t, client, config.httpClient())
+	config, _ = source()
+	assert.Equal(t, http.DefaultClient, config.httpClient())
+
+	_, err = WithHTTPClient(func() (*StorageSecretConfig, error) { return nil, errors.New("no secret") }, client)()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,263 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WebhookFailurePolicy ...
+type WebhookFailurePolicy string
+
+const (
+	// WebhookFailureIgnore admits the requests the webhook fails to review.
+	WebhookFailureIgnore WebhookFailurePolicy = "ignore"
+	// WebhookFailureFail rejects the requests the webhook fails to review.
+	WebhookFailureFail WebhookFailurePolicy = "fail"
+
+	// maxAdmissionReviewSize bounds the admission reviews read, which carry two nodes for updates.
+	maxAdmissionReviewSize = 3 << 20
+	// certificateReloadInterval is how often the serving certificate files are checked for changes.
+	certificateReloadInterval = 10 * time.Second
+)
+
+// ParseWebhookFailurePolicy ...
+func ParseWebhookFailurePolicy(policy string) (WebhookFailurePolicy, error) {
+	switch WebhookFailurePolicy(policy) {
+	case WebhookFailureIgnore, WebhookFailureFail:
+		return WebhookFailurePolicy(policy), nil
+	}
+	return "", fmt.Errorf("invalid webhook failure policy %q, must be one of %q, %q", policy, WebhookFailureIgnore, WebhookFailureFail)
+}
+
+// admissionReviewer reviews a single admission request.
+type admissionReviewer func(ctx context.Context, request *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error)
+
+// serveAdmissionReview decodes the admission review of the request, reviews it and writes the review response.
+// Requests which fail to be reviewed are admitted or rejected as configured by the failure policy.
+func serveAdmissionReview(w http.ResponseWriter, r *http.Request, logger *zap.Logger, failurePolicy WebhookFailurePolicy, review admissionReviewer) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxAdmissionReviewSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read admission review: %v", err), http.StatusBadRequest)
+		return
+	}
+	var admissionReview admissionv1.AdmissionReview
+	if err = json.Unmarshal(body, &admissionReview); err != nil || admissionReview.Request == nil {
+		http.Error(w, "invalid admission review", http.StatusBadRequest)
+		return
+	}
+
+	request := admissionReview.Request
+	response, err := review(r.Context(), request)
+	if err != nil {
+		logger.Error("Failed to review admission request", zap.String("workerNodeName", request.Name),
+			zap.String("operation", string(request.Operation)), zap.String("failurePolicy", string(failurePolicy)), zap.Error(err))
+		response = &admissionv1.AdmissionResponse{Allowed: failurePolicy == WebhookFailureIgnore}
+		if !response.Allowed {
+			response.Result = &metav1.Status{Code: http.StatusInternalServerError, Message: err.Error()}
+		}
+	}
+	response.UID = request.UID
+	admissionReview.Request = nil
+	admissionReview.Response = response
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(admissionReview); err != nil {
+		logger.Warn("Failed to write admission review response", zap.Error(err))
+	}
+}
+
+// decodeAdmissionNodes decodes the node and the old node of an admission request. The old node is nil for creations.
+func decodeAdmissionNodes(request *admissionv1.AdmissionRequest) (*v1.Node, *v1.Node, error) {
+	if request.Kind.Kind != "Node" {
+		return nil, nil, fmt.Errorf("unexpected kind %s, the webhook only reviews nodes", request.Kind.Kind)
+	}
+	node := &v1.Node{}
+	if err := json.Unmarshal(request.Object.Raw, node); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode node: %v", err)
+	}
+	if len(request.OldObject.Raw) == 0 {
+		return node, nil, nil
+	}
+	oldNode := &v1.Node{}
+	if err := json.Unmarshal(request.OldObject.Raw, oldNode); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode old node: %v", err)
+	}
+	return node, oldNode, nil
+}
+
+// LabelProtectionWebhook is a validating admission webhook rejecting node updates which change or remove the
+// labels managed by the updater, unless they are made by an allowed user or group, e.g. the updater's own
+// service account.
+type LabelProtectionWebhook struct {
+	Logger        *zap.Logger
+	AllowedUsers  []string
+	AllowedGroups []string
+	FailurePolicy WebhookFailurePolicy
+}
+
+// ServeHTTP ...
+func (h *LabelProtectionWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serveAdmissionReview(w, r, h.Logger, h.FailurePolicy, h.review)
+}
+
+func (h *LabelProtectionWebhook) review(_ context.Context, request *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error) {
+	if request.Operation != admissionv1.Update || h.allowed(request.UserInfo.Username, request.UserInfo.Groups) {
+		return &admissionv1.AdmissionResponse{Allowed: true}, nil
+	}
+	node, oldNode, err := decodeAdmissionNodes(request)
+	if err != nil {
+		return nil, err
+	}
+	if oldNode == nil {
+		return nil, fmt.Errorf("no old node in the update of node %s", request.Name)
+	}
+	changed := ProtectedLabelChanges(oldNode, node)
+	if len(changed) == 0 {
+		return &admissionv1.AdmissionResponse{Allowed: true}, nil
+	}
+	h.Logger.Warn("Rejected change of managed node labels", zap.String("workerNodeName", request.Name),
+		zap.String("user", request.UserInfo.Username), zap.Strings("labels", changed))
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Code:   http.StatusForbidden,
+			Reason: metav1.StatusReasonForbidden,
+			Message: fmt.Sprintf("labels %s of node %s are managed by vpc-node-label-updater and can't be changed by %s",
+				strings.Join(changed, ", "), request.Name, request.UserInfo.Username),
+		},
+	}, nil
+}
+
+// allowed returns true if the user or one of its groups is allowed to change the managed labels.
+func (h *LabelProtectionWebhook) allowed(username string, groups []string) bool {
+	for _, allowed := range h.AllowedUsers {
+		if allowed == username {
+			return true
+		}
+	}
+	for _, allowed := range h.AllowedGroups {
+		for _, group := range groups {
+			if allowed == group {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ProtectedLabelChanges returns the sorted keys of the protected labels of the old node which the update
+// changes or removes, and the managed labels annotation if it is changed. The protected labels are the
+// instance ID and topology labels, and the labels listed in the managed labels annotation of the old node.
+func ProtectedLabelChanges(oldNode, node *v1.Node) []string {
+	protected := map[string]bool{instanceIDLabelKey: true, topologyRegionLabelKey: true, topologyZoneLabelKey: true}
+	if managed, err := GetManagedLabels(oldNode); err == nil {
+		for key := range managed {
+			protected[key] = true
+		}
+	}
+	var changed []string
+	for key := range protected {
+		oldValue, ok := oldNode.Labels[key]
+		if !ok {
+			continue
+		}
+		if value, ok := node.Labels[key]; !ok || value != oldValue {
+			changed = append(changed, key)
+		}
+	}
+	// Dropping a key from the annotation would lift its protection.
+	oldAnnotation, ok := oldNode.Annotations[ManagedLabelsAnnotationKey]
+	if ok && node.Annotations[ManagedLabelsAnnotationKey] != oldAnnotation {
+		changed = append(changed, ManagedLabelsAnnotationKey)
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// CertificateReloader serves a TLS certificate read from files, and reads them again when they change,
+// e.g. when cert-manager renews the webhook serving certificate.
+type CertificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mutex       sync.Mutex
+	certificate *tls.Certificate
+	modTime     time.Time
+	checkedAt   time.Time
+}
+
+// NewCertificateReloader reads the certificate and key files.
+func NewCertificateReloader(certFile, keyFile string) (*CertificateReloader, error) {
+	reloader := &CertificateReloader{certFile: certFile, keyFile: keyFile}
+	if err := reloader.reload(time.Now()); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config. The previous certificate is kept while
+// the files are being replaced and fail to load.
+func (cr *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+	if now := time.Now(); now.Sub(cr.checkedAt) >= certificateReloadInterval {
+		_ = cr.reload(now) // #nosec G104: the previous certificate is served until the new one loads.
+	}
+	return cr.certificate, nil
+}
+
+// reload reads the certificate again if a file changed since the last read.
+func (cr *CertificateReloader) reload(now time.Time) error {
+	cr.checkedAt = now
+	modTime := time.Time{}
+	for _, file := range []string{cr.certFile, cr.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	if cr.certificate != nil && modTime.Equal(cr.modTime) {
+		return nil
+	}
+	certificate, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load serving certificate: %v", err)
+	}
+	cr.certificate = &certificate
+	cr.modTime = modTime
+	return nil
+}
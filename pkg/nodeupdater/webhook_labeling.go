@@ -0,0 +1,144 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// defaultMaxConcurrentLookups is the number of concurrent node lookups of the labeling webhook if not set.
+const defaultMaxConcurrentLookups = 10
+
+// errTooManyLookups is returned when the node lookups of the labeling webhook are all in use.
+var errTooManyLookups = errors.New("too many concurrent node lookups")
+
+// jsonPatchOperation is an operation of a JSON patch (RFC 6902).
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// NodeLabelingWebhook is a mutating admission webhook adding the labels of the node to the node creation, so
+// that the labels are present before any scheduling decision. Nodes whose lookup fails or exceeds the lookup
+// timeout are admitted unchanged and labeled asynchronously by the updater or the controller.
+type NodeLabelingWebhook struct {
+	// Updater looks up the node details, preferably with an instance cache. Its Node is ignored.
+	Updater *VpcNodeLabelUpdater
+	// LookupTimeout must be shorter than the timeout of the webhook configuration.
+	LookupTimeout time.Duration
+	// MaxConcurrentLookups limits the node lookups running at once, defaultMaxConcurrentLookups if not set.
+	MaxConcurrentLookups int
+	FailurePolicy        WebhookFailurePolicy
+
+	lookupsOnce sync.Once
+	lookups     chan struct{}
+}
+
+// ServeHTTP ...
+func (h *NodeLabelingWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serveAdmissionReview(w, r, h.Updater.Logger, h.FailurePolicy, h.review)
+}
+
+func (h *NodeLabelingWebhook) review(ctx context.Context, request *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error) {
+	if request.Operation != admissionv1.Create {
+		return &admissionv1.AdmissionResponse{Allowed: true}, nil
+	}
+	node, _, err := decodeAdmissionNodes(request)
+	if err != nil {
+		return nil, err
+	}
+	logger := h.Updater.Logger.With(zap.String("workerNodeName", node.Name))
+
+	nodeInfo, err := h.lookup(ctx, node.Name)
+	if err != nil {
+		logger.Warn("Admitting node without labels, the node is labeled asynchronously", zap.Error(err))
+		return &admissionv1.AdmissionResponse{Allowed: true}, nil
+	}
+
+	updater := *h.Updater
+	updater.Node = node.DeepCopy()
+	if err = updater.applyNodeLabels(nodeInfo); err != nil {
+		logger.Warn("Admitting node without labels, the node is labeled asynchronously", zap.Error(err))
+		return &admissionv1.AdmissionResponse{Allowed: true}, nil
+	}
+	// Adding an existing member replaces it, the whole maps are written.
+	patch, err := json.Marshal([]jsonPatchOperation{
+		{Op: "add", Path: "/metadata/labels", Value: updater.Node.Labels},
+		{Op: "add", Path: "/metadata/annotations", Value: updater.Node.Annotations},
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Labeled node at registration", zap.String("instanceID", nodeInfo.InstanceID))
+	patchType := admissionv1.PatchTypeJSONPatch
+	return &admissionv1.AdmissionResponse{Allowed: true, Patch: patch, PatchType: &patchType}, nil
+}
+
+// acquireLookup reserves one of the concurrent node lookups, and returns false if they are all in use.
+func (h *NodeLabelingWebhook) acquireLookup() bool {
+	h.lookupsOnce.Do(func() {
+		limit := h.MaxConcurrentLookups
+		if limit <= 0 {
+			limit = defaultMaxConcurrentLookups
+		}
+		h.lookups = make(chan struct{}, limit)
+	})
+	select {
+	case h.lookups <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// lookup gets the details of the node, giving up after the lookup timeout, when the lookup is cancelled. A lookup
+// holds its slot until it returns, so that lookups ignoring the cancellation can't pile up, e.g. during a VPC
+// outage.
+func (h *NodeLabelingWebhook) lookup(ctx context.Context, nodeName string) (*NodeInfo, error) {
+	if !h.acquireLookup() {
+		return nil, errTooManyLookups
+	}
+	type result struct {
+		nodeInfo *NodeInfo
+		err      error
+	}
+	done := make(chan result, 1)
+	lookupCtx, cancel := context.WithTimeout(ctx, h.LookupTimeout)
+	defer cancel()
+	go func() {
+		defer func() { <-h.lookups }()
+		nodeInfo, err := h.Updater.GetWorkerDetails(lookupCtx, nodeName)
+		done <- result{nodeInfo: nodeInfo, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.nodeInfo, r.err
+	case <-lookupCtx.Done():
+		return nil, lookupCtx.Err()
+	}
+}
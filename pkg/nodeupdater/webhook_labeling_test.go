@@ -0,0 +1,114 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	jsonpatch "gopkg.in/evanphx/json-patch.v4"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodeLabelingWebhook(t *testing.T) {
+	requests := 0
+	server := newFakeRiaasServer(t, testInstances(), &requests)
+	// The slow server answers once the test is over.
+	release := make(chan struct{})
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		http.Error(w, "too late", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(slowServer.Close)
+	t.Cleanup(func() { close(release) })
+
+	newNode := testNode("worker-2", map[string]string{"kubernetes.io/hostname": "worker-2"})
+	testCases := []struct {
+		name        string
+		serverURL   string
+		operation   admissionv1.Operation
+		busyLookups int
+		expLabeled  bool
+	}{
+		{name: "Node created", serverURL: server.URL, operation: admissionv1.Create, expLabeled: true},
+		{name: "Node updated", serverURL: server.URL, operation: admissionv1.Update},
+		{name: "Lookup timeout", serverURL: slowServer.URL, operation: admissionv1.Create},
+		{name: "Too many lookups", serverURL: server.URL, operation: admissionv1.Create, busyLookups: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		updater := initNodeLabelUpdater(t)
+		updater.Logger = zap.NewNop()
+		updater.StorageSecretConfig.RiaasEndpointURL, _ = url.Parse(tc.serverURL + "/v1/instances?generation=2")
+		updater.InstanceCache = NewInstanceCache(time.Minute)
+		webhook := &NodeLabelingWebhook{Updater: updater, LookupTimeout: 200 * time.Millisecond, MaxConcurrentLookups: 2}
+		for i := 0; i < tc.busyLookups; i++ {
+			assert.True(t, webhook.acquireLookup())
+		}
+
+		request := &admissionv1.AdmissionRequest{
+			UID:       "uid",
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Node"},
+			Name:      newNode.Name,
+			Operation: tc.operation,
+			Object:    rawNode(newNode),
+		}
+		response := postAdmissionReview(t, webhook, request)
+		if !assert.NotNil(t, response) {
+			continue
+		}
+		assert.True(t, response.Allowed)
+		if !tc.expLabeled {
+			assert.Empty(t, response.Patch)
+			continue
+		}
+
+		assert.Equal(t, admissionv1.PatchTypeJSONPatch, *response.PatchType)
+		patch, err := jsonpatch.DecodePatch(response.Patch)
+		assert.Nil(t, err)
+		patched, err := patch.Apply(request.Object.Raw)
+		assert.Nil(t, err)
+		labeled := &v1.Node{}
+		assert.Nil(t, json.Unmarshal(patched, labeled))
+		assert.Equal(t, "instance-2", labeled.Labels[instanceIDLabelKey])
+		assert.Equal(t, "us-south-2", labeled.Labels[topologyZoneLabelKey])
+		assert.Equal(t, "worker-2", labeled.Labels["kubernetes.io/hostname"])
+		assert.Contains(t, labeled.Annotations[ManagedLabelsAnnotationKey], instanceIDLabelKey)
+	}
+}
+
+func TestNodeLabelingWebhookCancelsLookup(t *testing.T) {
+	// The lookup stops retrying the unreachable server once the timeout is over, and frees its slot.
+	updater := initNodeLabelUpdater(t)
+	updater.Logger = zap.NewNop()
+	updater.StorageSecretConfig.RiaasEndpointURL, _ = url.Parse("http://127.0.0.1:1/v1/instances?generation=2")
+	webhook := &NodeLabelingWebhook{Updater: updater, LookupTimeout: 100 * time.Millisecond, MaxConcurrentLookups: 1}
+	_, err := webhook.lookup(context.TODO(), "worker-2")
+	assert.NotNil(t, err)
+	assert.Eventually(t, func() bool { return len(webhook.lookups) == 0 }, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,217 @@
+/**
+ * Copyright 2026 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodeupdater ...
+package nodeupdater
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// postAdmissionReview sends the admission request to the webhook and returns the response.
+func postAdmissionReview(t *testing.T, handler http.Handler, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	body, _ := json.Marshal(admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request:  request,
+	})
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/validate-node", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var review admissionv1.AdmissionReview
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &review))
+	if assert.NotNil(t, review.Response) {
+		assert.Equal(t, request.UID, review.Response.UID)
+	}
+	return review.Response
+}
+
+func rawNode(node *v1.Node) runtime.RawExtension {
+	raw, _ := json.Marshal(node)
+	return runtime.RawExtension{Raw: raw}
+}
+
+func TestProtectedLabelChanges(t *testing.T) {
+	managed := `{"custom-label":{}}`
+	oldNode := testNode("worker-1", map[string]string{
+		instanceIDLabelKey:   "instance-1",
+		topologyZoneLabelKey: "us-south-1",
+		"custom-label":       "a",
+		"other":              "b",
+	})
+	oldNode.Annotations = map[string]string{ManagedLabelsAnnotationKey: managed}
+
+	testCases := []struct {
+		name     string
+		mutate   func(node *v1.Node)
+		expected []string
+	}{
+		{name: "Unprotected label changed", mutate: func(node *v1.Node) { node.Labels["other"] = "c" }},
+		{name: "Label added", mutate: func(node *v1.Node) { node.Labels[topologyRegionLabelKey] = "us-south" }},
+		{name: "Zone changed", mutate: func(node *v1.Node) { node.Labels[topologyZoneLabelKey] = "us-south-2" }, expected: []string{topologyZoneLabelKey}},
+		{
+			name: "Instance ID and managed label removed",
+			mutate: func(node *v1.Node) {
+				delete(node.Labels, instanceIDLabelKey)
+				delete(node.Labels, "custom-label")
+			},
+			expected: []string{"custom-label", instanceIDLabelKey},
+		},
+		{name: "Annotation removed", mutate: func(node *v1.Node) { delete(node.Annotations, ManagedLabelsAnnotationKey) }, expected: []string{ManagedLabelsAnnotationKey}},
+	}
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		node := oldNode.DeepCopy()
+		tc.mutate(node)
+		assert.Equal(t, tc.expected, ProtectedLabelChanges(oldNode, node))
+	}
+}
+
+func TestLabelProtectionWebhook(t *testing.T) {
+	oldNode := testNode("worker-1", map[string]string{instanceIDLabelKey: "instance-1"})
+	changedNode := testNode("worker-1", map[string]string{instanceIDLabelKey: "instance-2"})
+
+	testCases := []struct {
+		name          string
+		request       *admissionv1.AdmissionRequest
+		failurePolicy WebhookFailurePolicy
+		allowed       bool
+	}{
+		{
+			name:    "Managed label changed by user",
+			request: &admissionv1.AdmissionRequest{Operation: admissionv1.Update, Object: rawNode(changedNode), OldObject: rawNode(oldNode), UserInfo: authenticationv1.UserInfo{Username: "alice"}},
+			allowed: false,
+		},
+		{
+			name:    "Managed label changed by updater",
+			request: &admissionv1.AdmissionRequest{Operation: admissionv1.Update, Object: rawNode(changedNode), OldObject: rawNode(oldNode), UserInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:kube-system:node-sa"}},
+			allowed: true,
+		},
+		{
+			name:    "Managed label changed by allowed group",
+			request: &admissionv1.AdmissionRequest{Operation: admissionv1.Update, Object: rawNode(changedNode), OldObject: rawNode(oldNode), UserInfo: authenticationv1.UserInfo{Username: "bob", Groups: []string{"node-admins"}}},
+			allowed: true,
+		},
+		{
+			name:    "Unchanged labels",
+			request: &admissionv1.AdmissionRequest{Operation: admissionv1.Update, Object: rawNode(oldNode), OldObject: rawNode(oldNode), UserInfo: authenticationv1.UserInfo{Username: "alice"}},
+			allowed: true,
+		},
+		{
+			name:          "Invalid node, fail open",
+			request:       &admissionv1.AdmissionRequest{Operation: admissionv1.Update, Object: runtime.RawExtension{Raw: []byte(`{"metadata":"invalid"}`)}, UserInfo: authenticationv1.UserInfo{Username: "alice"}},
+			failurePolicy: WebhookFailureIgnore,
+			allowed:       true,
+		},
+		{
+			name:          "Invalid node, fail closed",
+			request:       &admissionv1.AdmissionRequest{Operation: admissionv1.Update, Object: runtime.RawExtension{Raw: []byte(`{"metadata":"invalid"}`)}, UserInfo: authenticationv1.UserInfo{Username: "alice"}},
+			failurePolicy: WebhookFailureFail,
+			allowed:       false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("Test case: %s", tc.name)
+		webhook := &LabelProtectionWebhook{
+			Logger:        zap.NewNop(),
+			AllowedUsers:  []string{"system:serviceaccount:kube-system:node-sa"},
+			AllowedGroups: []string{"node-admins"},
+			FailurePolicy: tc.failurePolicy,
+		}
+		tc.request.UID = types.UID("uid-" + tc.name)
+		tc.request.Name = "worker-1"
+		tc.request.Kind = metav1.GroupVersionKind{Version: "v1", Kind: "Node"}
+		response := postAdmissionReview(t, webhook, tc.request)
+		if response != nil {
+			assert.Equal(t, tc.allowed, response.Allowed)
+		}
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate for the common name and its key to the files.
+func writeTestCertificate(t *testing.T, certFile, keyFile, commonName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	_, err := NewCertificateReloader(certFile, keyFile)
+	assert.NotNil(t, err)
+
+	writeTestCertificate(t, certFile, keyFile, "first")
+	reloader, err := NewCertificateReloader(certFile, keyFile)
+	assert.Nil(t, err)
+	servedName := func() string {
+		certificate, err := reloader.GetCertificate(nil)
+		assert.Nil(t, err)
+		parsed, err := x509.ParseCertificate(certificate.Certificate[0])
+		assert.Nil(t, err)
+		return parsed.Subject.CommonName
+	}
+	assert.Equal(t, "first", servedName())
+
+	// The renewed certificate is served once the reload interval has passed.
+	writeTestCertificate(t, certFile, keyFile, "second")
+	later := time.Now().Add(time.Minute)
+	assert.Nil(t, os.Chtimes(certFile, later, later))
+	assert.Equal(t, "first", servedName())
+	reloader.checkedAt = time.Time{}
+	assert.Equal(t, "second", servedName())
+
+	// A broken certificate keeps the previous one.
+	assert.Nil(t, os.WriteFile(certFile, []byte("invalid"), 0600))
+	later = later.Add(time.Minute)
+	assert.Nil(t, os.Chtimes(certFile, later, later))
+	reloader.checkedAt = time.Time{}
+	assert.Equal(t, "second", servedName())
+}